@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	bw "bitwarden-tui/internal"
+	"bitwarden-tui/internal/ui"
+)
+
+type attachmentSavedMsg struct{ path string }
+type attachmentsChangedMsg struct {
+	item   bw.Item
+	status string
+}
+
+// overwriteMsg asks before a download replaces an existing file.
+type overwriteMsg struct {
+	item       bw.Item
+	attachment bw.Attachment
+	path       string
+}
+
+// == CMD ==
+
+// saveAttachment downloads to path, unless a file is already there.
+func (m *model) saveAttachment(item bw.Item, attachment bw.Attachment, path string) tea.Cmd {
+	path = expandHome(strings.TrimSpace(path))
+	if path == "" {
+		return nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, attachment.FileName)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return func() tea.Msg {
+			return overwriteMsg{item: item, attachment: attachment, path: path}
+		}
+	}
+	return m.downloadAttachment(item, attachment, path)
+}
+
+func (m *model) downloadAttachment(item bw.Item, attachment bw.Attachment, path string) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.DownloadAttachment(ctx, item.Id, attachment.Id, path)
+		if err != nil {
+			return failed("Failed to download attachment", err)
+		}
+		return attachmentSavedMsg{path: path}
+	}
+}
+
+func (m *model) uploadAttachment(item bw.Item, path string) tea.Cmd {
+	path = expandHome(strings.TrimSpace(path))
+	if path == "" {
+		return nil
+	}
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		if _, err := os.Stat(path); err != nil {
+			return errorMsg{errors.New("No such file!")}
+		}
+		updated, err := m.vault.UploadAttachment(ctx, item.Id, path)
+		if err != nil || updated == nil {
+			return failed("Failed to attach file", err)
+		}
+		return attachmentsChangedMsg{item: *updated, status: "attached " + filepath.Base(path)}
+	}
+}
+
+func (m *model) deleteAttachment(item bw.Item, attachment bw.Attachment) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.DeleteAttachment(ctx, item.Id, attachment.Id)
+		if err != nil {
+			return failed("Failed to delete attachment", err)
+		}
+		updated, err := m.vault.GetItem(ctx, item.Id)
+		if err != nil || updated == nil {
+			return failed("Failed to fetch item", err)
+		}
+		return attachmentsChangedMsg{item: *updated, status: "deleted " + attachment.FileName}
+	}
+}
+
+// == UPDATE ==
+
+// updateAttachments handles the attachment messages of the item view.
+func (m *model) updateAttachments(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case item.DownloadMsg:
+		m.prompt = newTextPrompt("Save "+msg.Attachment.FileName+" to", msg.Attachment.FileName, func(path string) tea.Cmd {
+			return m.saveAttachment(msg.Item, msg.Attachment, path)
+		})
+	case overwriteMsg:
+		m.confirm = &confirmation{
+			prompt: msg.path + " already exists. Overwrite it?",
+			onYes: func() tea.Cmd {
+				return m.downloadAttachment(msg.item, msg.attachment, msg.path)
+			},
+		}
+	case attachmentSavedMsg:
+		return m.itemView.item.NewStatusMessage("saved " + msg.path)
+	case item.AttachMsg:
+		m.prompt = newTextPrompt("File to attach to "+msg.Item.Name, "", func(path string) tea.Cmd {
+			return m.uploadAttachment(msg.Item, path)
+		})
+	case item.DeleteAttachmentMsg:
+		m.confirm = &confirmation{
+			prompt: "Delete attachment " + msg.Attachment.FileName + "? This can't be undone.",
+			onYes: func() tea.Cmd {
+				return m.deleteAttachment(msg.Item, msg.Attachment)
+			},
+		}
+	case attachmentsChangedMsg:
+		m.itemView.item.UpdateItem(msg.item)
+		return m.itemView.item.NewStatusMessage(msg.status)
+	}
+	return nil
+}
+
+// expandHome resolves a leading ~ to the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+
+	bw "bitwarden-tui/internal"
+)
+
+// authState is the step of getting into the vault the PASSINPUT view is at.
+// It follows what bw status reports.
+type authState int
+
+const (
+	authChecking authState = iota
+	authLogin
+	authTwoFactor
+	authUnlock
+)
+
+var twoFactorMethods = []struct {
+	method bw.TwoFactorMethod
+	name   string
+}{
+	{bw.TwoFactorAuthenticator, "Authenticator app"},
+	{bw.TwoFactorEmail, "Email"},
+	{bw.TwoFactorYubiKey, "YubiKey OTP"},
+}
+
+type inputView struct {
+	state      authState
+	emailInput textinput.Model
+	textInput  textinput.Model
+	codeInput  textinput.Model
+	// method indexes twoFactorMethods
+	method    int
+	status    *bw.Status
+	spinner   spinner.Model
+	isLoading bool
+	loading   string
+	notice    string
+	error     error
+}
+
+type statusMsg bw.Status
+type twoFactorMsg struct {
+	// codeSent is set when the server emailed a code
+	codeSent bool
+}
+type apiKeyLoggedInMsg struct{}
+
+func newAuthInput(placeholder string) textinput.Model {
+	input := textinput.New()
+	input.Placeholder = placeholder
+	input.Prompt = "🢒 "
+	input.PromptStyle = l.NewStyle().Foreground(l.Color(brightYellow))
+	return input
+}
+
+func newInputView() inputView {
+	passwordInput := newAuthInput("Master password")
+	passwordInput.EchoMode = textinput.EchoPassword
+	passwordInput.EchoCharacter = '•'
+	inputViewSpinner := spinner.New()
+	inputViewSpinner.Spinner = spinner.MiniDot
+	inputViewSpinner.Style = l.NewStyle().Foreground(l.Color("8"))
+	return inputView{
+		state:      authChecking,
+		emailInput: newAuthInput("Email address"),
+		textInput:  passwordInput,
+		codeInput:  newAuthInput("Verification code"),
+		spinner:    inputViewSpinner,
+		isLoading:  true,
+		loading:    "Checking vault status…",
+	}
+}
+
+// setState moves to another step and focuses its first input.
+func (v *inputView) setState(state authState) tea.Cmd {
+	v.state = state
+	v.isLoading = false
+	v.emailInput.Blur()
+	v.textInput.Blur()
+	v.codeInput.Blur()
+	switch state {
+	case authLogin:
+		if v.emailInput.Value() == "" {
+			return v.emailInput.Focus()
+		}
+		return v.textInput.Focus()
+	case authTwoFactor:
+		v.codeInput.SetValue("")
+		return v.codeInput.Focus()
+	case authUnlock:
+		return v.textInput.Focus()
+	}
+	return nil
+}
+
+// == CMD ==
+
+func (m *model) checkStatus() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		status, err := m.vault.Status(ctx)
+		if err != nil || status == nil {
+			return failed("Couldn't read the vault status", err)
+		}
+		return statusMsg(*status)
+	}
+}
+
+func (m *model) unlock() tea.Cmd {
+	password := m.inputView.textInput.Value()
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.Unlock(ctx, password)
+		if errors.Is(err, bw.ErrInvalidPassword) {
+			return errorMsg{errors.New("Invalid master password!")}
+		} else if err != nil {
+			return failed("Failed to unlock", err)
+		}
+		return sessionMsg{}
+	}
+}
+
+func (m *model) login(twoFactor *bw.TwoFactor) tea.Cmd {
+	email := strings.TrimSpace(m.inputView.emailInput.Value())
+	password := m.inputView.textInput.Value()
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.Login(ctx, email, password, twoFactor)
+		switch {
+		case err == nil:
+			return sessionMsg{}
+		case errors.Is(err, bw.ErrTwoFactorRequired):
+			codeSent := twoFactor != nil && twoFactor.Method == bw.TwoFactorEmail
+			return twoFactorMsg{codeSent: codeSent}
+		case errors.Is(err, bw.ErrInvalidTwoFactor):
+			return errorMsg{errors.New("Invalid two-step login code!")}
+		case errors.Is(err, bw.ErrInvalidPassword):
+			return errorMsg{errors.New("Invalid email or master password!")}
+		}
+		return failed("Login failed", err)
+	}
+}
+
+// cachedItems are the items the cache held when the vault unlocked.
+func (m *model) cachedItems() ([]bw.Item, bool) {
+	if m.cache == nil {
+		return nil, false
+	}
+	return m.cache.Items(m.listView.filter)
+}
+
+// loginAPIKey logs in with BW_CLIENTID and BW_CLIENTSECRET. The vault still
+// has to be unlocked afterwards.
+func (m *model) loginAPIKey() tea.Cmd {
+	clientId, clientSecret := os.Getenv("BW_CLIENTID"), os.Getenv("BW_CLIENTSECRET")
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.LoginAPIKey(ctx, clientId, clientSecret)
+		if err != nil {
+			return failed("API key login failed", err)
+		}
+		return apiKeyLoggedInMsg{}
+	}
+}
+
+// sessionKey is the session to start with: the --session flag, else the
+// contents of the file BW_SESSION_FILE names. Empty leaves BW_SESSION as it
+// is. The file keeps the key out of argv, where any user can read it.
+func sessionKey(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	path := os.Getenv("BW_SESSION_FILE")
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func hasAPIKey() bool {
+	return os.Getenv("BW_CLIENTID") != "" && os.Getenv("BW_CLIENTSECRET") != ""
+}
+
+// == UPDATE ==
+
+func (m model) updateAuth(msg tea.Msg) (tea.Model, tea.Cmd) {
+	v := &m.inputView
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" || msg.String() == "ctrl+d" {
+			return m, tea.Quit
+		}
+		if v.isLoading {
+			return m, nil
+		}
+		if key.Matches(msg, m.listView.keys.settings) {
+			return m, m.openSettings()
+		}
+		if key.Matches(msg, m.listView.keys.profiles) && m.profileView.open != nil {
+			return m, m.openProfiles()
+		}
+		retry := v.state == authChecking && v.error != nil
+		v.error = nil
+		switch v.state {
+		case authChecking:
+			if retry && msg.String() == "enter" {
+				v.isLoading = true
+				return m, tea.Batch(v.spinner.Tick, m.checkStatus())
+			}
+		case authLogin:
+			switch msg.String() {
+			case "tab", "shift+tab", "up", "down":
+				if v.emailInput.Focused() {
+					v.emailInput.Blur()
+					return m, v.textInput.Focus()
+				}
+				v.textInput.Blur()
+				return m, v.emailInput.Focus()
+			case "enter":
+				if v.emailInput.Focused() {
+					v.emailInput.Blur()
+					return m, v.textInput.Focus()
+				}
+				v.isLoading = true
+				v.loading = "Logging in…"
+				return m, tea.Batch(v.spinner.Tick, m.login(nil))
+			}
+		case authTwoFactor:
+			switch msg.String() {
+			case "esc":
+				v.notice = ""
+				return m, v.setState(authLogin)
+			case "left", "right":
+				delta := 1
+				if msg.String() == "left" {
+					delta = -1
+				}
+				v.method = (v.method + delta + len(twoFactorMethods)) % len(twoFactorMethods)
+				return m, nil
+			case "enter":
+				twoFactor := &bw.TwoFactor{
+					Method: twoFactorMethods[v.method].method,
+					Code:   strings.TrimSpace(v.codeInput.Value()),
+				}
+				v.isLoading = true
+				v.loading = "Verifying…"
+				if twoFactor.Code == "" && twoFactor.Method == bw.TwoFactorEmail {
+					v.loading = "Sending code…"
+				}
+				return m, tea.Batch(v.spinner.Tick, m.login(twoFactor))
+			}
+		case authUnlock:
+			if msg.String() == "enter" {
+				v.isLoading = true
+				v.loading = ""
+				return m, tea.Batch(v.spinner.Tick, m.unlock())
+			}
+		}
+	case statusMsg:
+		status := bw.Status(msg)
+		v.status = &status
+		if status.UserEmail != "" && v.emailInput.Value() == "" {
+			v.emailInput.SetValue(status.UserEmail)
+		}
+		if server := m.wantServer; server != "" {
+			m.wantServer = ""
+			if !bw.SameServer(server, status.ServerUrl) {
+				// told no, carry on with the server as it is
+				onNo := func() tea.Msg { return msg }
+				return m, m.confirmServer(bw.ServerConfig{Url: server}, onNo)
+			}
+		}
+		switch status.Status {
+		case bw.StatusUnauthenticated:
+			if hasAPIKey() {
+				v.loading = "Logging in with API key…"
+				return m, m.loginAPIKey()
+			}
+			return m, v.setState(authLogin)
+		case bw.StatusUnlocked:
+			// an existing session is still valid
+			v.loading = "Loading items…"
+			return m, m.getItems()
+		default:
+			return m, v.setState(authUnlock)
+		}
+	case apiKeyLoggedInMsg:
+		v.loading = "Checking vault status…"
+		return m, m.checkStatus()
+	case twoFactorMsg:
+		v.notice = "Two-step login is required."
+		if msg.codeSent {
+			v.notice = "A verification code was sent to your email."
+		}
+		if v.state == authTwoFactor {
+			v.isLoading = false
+			return m, nil
+		}
+		return m, v.setState(authTwoFactor)
+	case sessionMsg:
+		if items, ok := m.cachedItems(); ok {
+			openCmd := m.openVault(items)
+			if m.cache.Offline() {
+				statusCmd := m.listView.list.NewStatusMessage("offline, showing cached items read-only")
+				return m, tea.Batch(openCmd, statusCmd)
+			}
+			// the fresh items replace the cached ones once they are in
+			spinnerCmd := m.listView.list.StartSpinner()
+			return m, tea.Batch(openCmd, spinnerCmd, m.getItems())
+		}
+		v.loading = "Loading items…"
+		return m, m.getItems()
+	case itemsMsg:
+		return m, m.openVault(msg)
+	case errorMsg:
+		v.error = msg.err
+		v.isLoading = false
+		m.listView.list.StopSpinner()
+		switch v.state {
+		case authChecking:
+			if v.status == nil {
+				break
+			}
+			switch v.status.Status {
+			case bw.StatusUnauthenticated:
+				// a failed API key login falls back to the login form
+				return m, v.setState(authLogin)
+			case bw.StatusUnlocked:
+				// the session wasn't usable after all
+				return m, v.setState(authUnlock)
+			}
+		case authTwoFactor:
+			v.codeInput.SetValue("")
+		default:
+			v.textInput.SetValue("")
+		}
+		return m, nil
+	}
+	var (
+		spinnerCmd, inputCmd tea.Cmd
+	)
+	v.spinner, spinnerCmd = v.spinner.Update(msg)
+	switch {
+	case v.emailInput.Focused():
+		v.emailInput, inputCmd = v.emailInput.Update(msg)
+	case v.textInput.Focused():
+		v.textInput, inputCmd = v.textInput.Update(msg)
+	case v.codeInput.Focused():
+		v.codeInput, inputCmd = v.codeInput.Update(msg)
+	}
+	return m, tea.Batch(inputCmd, spinnerCmd)
+}
+
+// startOver wipes the vault and all that is known of its account, then
+// goes back to checking the vault status. It follows switching to another
+// server or profile.
+func (m *model) startOver() tea.Cmd {
+	m.wipe()
+	m.resume = resumePoint{}
+	m.listView.filter = bw.FilterOptions{}
+	m.lastSync = nil
+	m.setListTitle()
+
+	m.view = PASSINPUT
+	v := &m.inputView
+	v.setState(authChecking)
+	v.status = nil
+	v.error = nil
+	v.notice = ""
+	v.isLoading = true
+	v.loading = "Checking vault status…"
+	return tea.Batch(v.spinner.Tick, m.checkStatus())
+}
+
+// openVault leaves the auth screen for the item list, showing items.
+func (m *model) openVault(items []bw.Item) tea.Cmd {
+	v := &m.inputView
+	m.view = PASSLIST
+	v.isLoading = false
+	v.notice = ""
+	v.textInput.SetValue("")
+	v.codeInput.SetValue("")
+	listCmd := m.listView.list.SetItems(listItemsFromBwItems(items))
+	resumeCmd := m.resumeAfterUnlock()
+	m.listView.selectPending()
+	return tea.Batch(listCmd, resumeCmd, m.startIdleTimer(), m.scheduleSync(), m.getLastSync())
+}
+
+// == VIEW ==
+
+func renderInput(m model) string {
+	v := m.inputView
+	var b strings.Builder
+	if v.isLoading {
+		b.WriteString(v.spinner.View() + " ")
+		titleStyle.MarginLeft(0)
+	} else {
+		titleStyle.MarginLeft(2)
+	}
+	b.WriteString(titleStyle.Render("BITWARDEN"))
+
+	hint := func(s string) string {
+		return "\n\n" + itemLabelStyle.Copy().MarginLeft(2).Render(s)
+	}
+	switch v.state {
+	case authChecking:
+		b.WriteString(hint(v.loading))
+		if v.error != nil {
+			b.WriteString(hint("Press enter to try again"))
+		}
+	case authLogin:
+		b.WriteString(hint("Log in to your Bitwarden account"))
+		b.WriteString("\n\n" + v.emailInput.View())
+		b.WriteString("\n" + v.textInput.View())
+	case authTwoFactor:
+		b.WriteString(hint(v.notice))
+		method := selectedPropertyStyle.Render("‹ " + twoFactorMethods[v.method].name + " ›")
+		b.WriteString("\n\n  " + itemLabelStyle.Render("Method") + method)
+		b.WriteString("\n" + v.codeInput.View())
+		if twoFactorMethods[v.method].method == bw.TwoFactorEmail {
+			b.WriteString(hint("Leave the code empty to have one sent"))
+		}
+	case authUnlock:
+		if v.status != nil && v.status.UserEmail != "" {
+			b.WriteString(hint("Unlock the vault of " + v.status.UserEmail))
+		}
+		b.WriteString("\n\n" + v.textInput.View())
+		if addr, ok := tcpServe(m.vault); ok {
+			b.WriteString("\n\n" + l.NewStyle().MarginLeft(2).Foreground(l.Color("11")).Render(
+				"bw serve listens on "+addr+", any user of this machine can read the vault once it's unlocked"))
+		}
+	}
+	if v.status != nil && (v.state == authLogin || v.state == authUnlock) {
+		server := v.status.ServerUrl
+		if server == "" {
+			server = bw.DefaultServer
+		}
+		b.WriteString(hint("Server " + server + " · " + m.listView.keys.settings.Help().Key + " to change"))
+		if m.profileView.open != nil {
+			b.WriteString("\n" + itemLabelStyle.Copy().MarginLeft(2).Render(
+				"Profile "+m.profileView.current+" · "+m.listView.keys.profiles.Help().Key+" to switch"))
+		}
+	}
+	if v.error != nil {
+		b.WriteString("\n\n" + l.NewStyle().Foreground(l.Color("9")).Render(errorText(v.error)))
+	}
+	return appStyle.Render(b.String())
+}
+
+// tcpServe is the address of the bw serve vault talks to, when that is a
+// TCP port rather than a socket only the user can reach.
+func tcpServe(vault bw.Vault) (string, bool) {
+	if cache, ok := vault.(*bw.Cache); ok {
+		vault = cache.Vault
+	}
+	serve, ok := vault.(*bw.Serve)
+	if !ok || !serve.OverTCP() {
+		return "", false
+	}
+	return serve.Addr, true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	bw "bitwarden-tui/internal"
+)
+
+// newLoggedOutModel is newTestModel once its vault logged out. Logging in
+// asks for code as a second step, unless it is empty.
+func newLoggedOutModel(t *testing.T, code string) (model, *bw.Memory) {
+	t.Helper()
+	m, vault, _ := newTestModel(t)
+	vault.LoggedOut = true
+	vault.TwoFactorCode = code
+	m = drain(t, m, m.checkStatus())
+	if m.inputView.state != authLogin {
+		t.Fatalf("state %v for a logged out vault, want the login form", m.inputView.state)
+	}
+	return m, vault
+}
+
+func TestLogin(t *testing.T) {
+	m, _ := newLoggedOutModel(t, "")
+	if got := m.inputView.emailInput.Value(); got != "user@example.com" {
+		t.Errorf("email = %q, want the one bw status reported", got)
+	}
+
+	m = typeText(t, m, "wrong")
+	m = press(t, m, tea.KeyEnter)
+	if m.inputView.state != authLogin || m.inputView.error == nil || m.inputView.error.Error() != "Invalid email or master password!" {
+		t.Fatalf("state %v with error %v after a wrong password", m.inputView.state, m.inputView.error)
+	}
+
+	m = typeText(t, m, "hunter2")
+	m = press(t, m, tea.KeyEnter)
+	if m.view != PASSLIST || len(m.listView.list.Items()) != 2 {
+		t.Errorf("view %v with %d items after logging in, want the list", m.view, len(m.listView.list.Items()))
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	m, _ := newLoggedOutModel(t, "123456")
+	m = typeText(t, m, "hunter2")
+	m = press(t, m, tea.KeyEnter)
+	if m.inputView.state != authTwoFactor || m.inputView.notice != "Two-step login is required." {
+		t.Fatalf("state %v with notice %q, want the two-step form", m.inputView.state, m.inputView.notice)
+	}
+
+	m = typeText(t, m, "000000")
+	m = press(t, m, tea.KeyEnter)
+	if m.inputView.state != authTwoFactor || m.inputView.error == nil || m.inputView.error.Error() != "Invalid two-step login code!" {
+		t.Fatalf("state %v with error %v after a wrong code", m.inputView.state, m.inputView.error)
+	}
+	if m.inputView.codeInput.Value() != "" {
+		t.Errorf("the wrong code %q is still there", m.inputView.codeInput.Value())
+	}
+
+	m = typeText(t, m, "123456")
+	m = press(t, m, tea.KeyEnter)
+	if m.view != PASSLIST {
+		t.Errorf("view %v after the right code, want the list", m.view)
+	}
+}
+
+func TestTwoFactorEmailAndBack(t *testing.T) {
+	m, _ := newLoggedOutModel(t, "123456")
+	m = typeText(t, m, "hunter2")
+	m = press(t, m, tea.KeyEnter)
+
+	m = press(t, m, tea.KeyRight)
+	if method := twoFactorMethods[m.inputView.method].method; method != bw.TwoFactorEmail {
+		t.Fatalf("method %v after right, want email", method)
+	}
+	// no code has the server send one
+	m = press(t, m, tea.KeyEnter)
+	if m.inputView.state != authTwoFactor || m.inputView.notice != "A verification code was sent to your email." {
+		t.Fatalf("state %v with notice %q after asking for a code", m.inputView.state, m.inputView.notice)
+	}
+
+	m = press(t, m, tea.KeyEsc)
+	if m.inputView.state != authLogin {
+		t.Errorf("state %v after esc, want the login form", m.inputView.state)
+	}
+}
+
+func TestAPIKeyLogin(t *testing.T) {
+	t.Setenv("BW_CLIENTID", "user.client")
+	t.Setenv("BW_CLIENTSECRET", "secret")
+	m, vault, _ := newTestModel(t)
+	vault.LoggedOut = true
+	m = drain(t, m, m.checkStatus())
+	if vault.LoggedOut || m.inputView.state != authUnlock {
+		t.Fatalf("state %v after logging in with the API key, want the unlock screen", m.inputView.state)
+	}
+	m = unlock(t, m)
+	if m.view != PASSLIST {
+		t.Errorf("view %v after unlocking, want the list", m.view)
+	}
+}
+
+func TestSessionNeedsBw(t *testing.T) {
+	if _, err := newVault("api", defaultProfile, "SESSIONKEY", "", false); err == nil {
+		t.Error("the api backend took a bw session key")
+	}
+	if _, err := newVault("api", defaultProfile, "", "", false); err != nil {
+		t.Errorf("the api backend without a session key: %v", err)
+	}
+}
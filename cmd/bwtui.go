@@ -2,8 +2,11 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	l "github.com/charmbracelet/lipgloss"
 
 	bw "bitwarden-tui/internal"
+	"bitwarden-tui/internal/clipboard"
 	"bitwarden-tui/internal/ui"
 )
 
@@ -56,9 +60,19 @@ func (i listItem) Description() string { return i.description }
 func (i listItem) FilterValue() string { return i.title + " " + i.description }
 
 type listKeyMap struct {
-	newItem  key.Binding
-	openItem key.Binding
-	sync     key.Binding
+	newItem   key.Binding
+	openItem  key.Binding
+	sync      key.Binding
+	delete    key.Binding
+	purge     key.Binding
+	trash     key.Binding
+	folders   key.Binding
+	move      key.Binding
+	generator key.Binding
+	sends     key.Binding
+	lock      key.Binding
+	settings  key.Binding
+	profiles  key.Binding
 }
 
 func newListKeyMap() *listKeyMap {
@@ -75,6 +89,46 @@ func newListKeyMap() *listKeyMap {
 			key.WithKeys("s"),
 			key.WithHelp("s", "sync vault"),
 		),
+		delete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "move to trash"),
+		),
+		purge: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "delete permanently"),
+		),
+		trash: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "open trash"),
+		),
+		folders: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "folders"),
+		),
+		move: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "move to folder"),
+		),
+		generator: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "password generator"),
+		),
+		sends: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "sends"),
+		),
+		lock: key.NewBinding(
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "lock vault"),
+		),
+		settings: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "server settings"),
+		),
+		profiles: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "switch profile"),
+		),
 	}
 }
 
@@ -84,15 +138,16 @@ const (
 	PASSINPUT view = iota
 	PASSLIST
 	PASSITEM
+	PASSNEW
+	TRASHLIST
+	FOLDERLIST
+	GENERATOR
+	SENDLIST
+	SENDFORM
+	SETTINGS
+	PROFILELIST
 )
 
-type inputView struct {
-	textInput textinput.Model
-	spinner   spinner.Model
-	isLoading bool
-	error     error
-}
-
 type itemView struct {
 	item item.Model
 }
@@ -100,122 +155,358 @@ type itemView struct {
 type listView struct {
 	list list.Model
 	keys *listKeyMap
+	// selectId is the item to select once the next item list arrives
+	selectId string
+	filter   bw.FilterOptions
 }
 
 type model struct {
-	view      view
-	listView  listView
-	inputView inputView
-	itemView  itemView
-	bwContext *bw.Context
+	view          view
+	listView      listView
+	inputView     inputView
+	itemView      itemView
+	formView      formView
+	trashView     trashView
+	folderView    folderView
+	generatorView generatorView
+	sendsView     sendsView
+	sendFormView  sendFormView
+	settingsView  settingsView
+	profileView   profileView
+	vault         bw.Vault
+	// cache, when set, is what vault reads through
+	cache *bw.Cache
+	calls *calls
+
+	// confirm and prompt, when set, are asked over the current view
+	confirm *confirmation
+	prompt  *textPrompt
+	width   int
+	height  int
+
+	// lockAfter is the idle time after which the vault locks, 0 for never
+	lockAfter    time.Duration
+	lastActivity time.Time
+	idleTimers   int
+	resume       resumePoint
+
+	// syncEvery is how often the vault syncs in the background, 0 for never
+	syncEvery  time.Duration
+	syncTimers int
+	syncing    bool
+	lastSync   *time.Time
+
+	// wantServer is the server asked for on the command line, switched to
+	// once the vault status is known
+	wantServer string
+
+	// clipboard is shared with the item view, so that every copy is cleared
+	clipboard      *clipboard.Guard
+	clipboardTicks int
+
+	// lastError is the latest failure. errorDetails is the one whose
+	// details are open, which later failures leave as it is.
+	lastError    error
+	errorDetails error
 }
 
 // == MSG ==
 
-type sessionMsg *bw.Context
+type sessionMsg struct{}
 type itemMsg bw.Item
 type itemsMsg []bw.Item
+type foldersMsg []bw.Folder
+type itemCreatedMsg bw.Item
+type itemEditedMsg bw.Item
+type itemDeletedMsg struct {
+	item      bw.Item
+	permanent bool
+}
+type itemRestoredMsg bw.Item
+type trashMsg []bw.Item
+type itemMovedMsg bw.Item
+type folderSavedMsg bw.Folder
+type folderDeletedMsg bw.Folder
 type errorMsg struct{ err error }
 
 // == CMD ==
 
-func raiseErr(s string) tea.Cmd {
+func (m *model) getItem() tea.Cmd {
+	i := m.listView.list.SelectedItem().(listItem)
+	return m.fetchItem(i.Id())
+}
+
+func (m *model) fetchItem(id string) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
 	return func() tea.Msg {
-		return errorMsg{errors.New(s)}
+		defer done()
+		item, err := m.vault.GetItem(ctx, id)
+		if err != nil || item == nil {
+			return failed("Failed to fetch item", err)
+		}
+		return itemMsg(*item)
 	}
 }
 
-func (m *model) login() tea.Cmd {
+func (m *model) getItems() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
 	return func() tea.Msg {
-		ctx, err := bw.InitializeClient(m.inputView.textInput.Value())
+		defer done()
+		items, err := m.vault.GetItems(ctx, m.listView.filter)
 		if err != nil {
-			m.inputView.isLoading = false
-			return errorMsg{errors.New("Invalid master password!")}
+			return failed("Failed to fetch items", err)
 		}
-		m.inputView.isLoading = false
-		return sessionMsg(ctx)
+		return itemsMsg(items)
 	}
 }
 
-func (m *model) getItem() tea.Cmd {
+func (m *model) getFolders() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
 	return func() tea.Msg {
-		i := m.listView.list.SelectedItem().(listItem)
-		item, err := m.bwContext.GetItem(i.Id())
-		if err != nil || item == nil {
-			return errorMsg{errors.New("Failed to fetch item!")}
+		defer done()
+		folders, err := m.vault.GetFolders(ctx)
+		if err != nil {
+			return failed("Failed to fetch folders", err)
 		}
-		return itemMsg(*item)
+		return foldersMsg(folders)
 	}
 }
 
-func (m *model) getItems() tea.Cmd {
+func (m *model) createItem(item bw.Item) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
 	return func() tea.Msg {
-		items, err := m.bwContext.GetItems(bw.FilterOptions{})
+		defer done()
+		created, err := m.vault.CreateItem(ctx, item)
+		if err != nil || created == nil {
+			return failed("Failed to create item", err)
+		}
+		return itemCreatedMsg(*created)
+	}
+}
+
+func (m *model) editItem(item bw.Item) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		edited, err := m.vault.EditItem(ctx, item)
+		if err != nil || edited == nil {
+			return failed("Failed to save item", err)
+		}
+		return itemEditedMsg(*edited)
+	}
+}
+
+func (m *model) deleteItem(item bw.Item, permanent bool) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.DeleteItem(ctx, item.Id, permanent)
 		if err != nil {
-			return errorMsg{errors.New("Failed to fetch items")}
+			return failed("Failed to delete item", err)
 		}
-		return itemsMsg(items)
+		return itemDeletedMsg{item: item, permanent: permanent}
 	}
 }
 
-func (m *model) sync() tea.Cmd {
-	err := m.bwContext.Sync()
-	if err != nil {
-		return raiseErr("Sync failed!")
+// confirmDelete deletes right away when moving to the trash, and asks first
+// when the item would be gone for good.
+func (m *model) confirmDelete(item bw.Item, permanent bool) tea.Cmd {
+	if !permanent {
+		return m.deleteItem(item, false)
+	}
+	m.confirm = &confirmation{
+		prompt: "Permanently delete " + item.Name + "? This can't be undone.",
+		onYes: func() tea.Cmd {
+			return m.deleteItem(item, true)
+		},
 	}
-	m.listView.list.StopSpinner()
-	return m.getItems()
+	return nil
 }
 
-func newModel() model {
-	var (
-		listKeys = newListKeyMap()
-	)
+func (m *model) restoreItem(item bw.Item) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.RestoreItem(ctx, item.Id)
+		if err != nil {
+			return failed("Failed to restore item", err)
+		}
+		return itemRestoredMsg(item)
+	}
+}
+
+func (m *model) getTrash() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		items, err := m.vault.GetTrash(ctx)
+		if err != nil {
+			return failed("Failed to fetch trash", err)
+		}
+		return trashMsg(items)
+	}
+}
+
+// moveItem puts an item into a folder, folderId "" or noFolder for none.
+func (m *model) moveItem(item bw.Item, folderId string) tea.Cmd {
+	if folderId == noFolder {
+		folderId = ""
+	}
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		// the list only knows ids and names, so start from the stored item
+		current, err := m.vault.GetItem(ctx, item.Id)
+		if err != nil || current == nil {
+			return failed("Failed to fetch item", err)
+		}
+		current.FolderId = folderId
+		moved, err := m.vault.EditItem(ctx, *current)
+		if err != nil || moved == nil {
+			return failed("Failed to move item", err)
+		}
+		return itemMovedMsg(*moved)
+	}
+}
+
+// openFolders switches to the folder list, to filter the item list or,
+// given an item, to move it.
+func (m *model) openFolders(moving *bw.Item) tea.Cmd {
+	m.folderView.moving = moving
+	m.folderView.returnTo = m.view
+	m.view = FOLDERLIST
+	m.folderView.list.Title = "FOLDERS"
+	if moving != nil {
+		m.folderView.list.Title = "MOVE " + moving.Name + " TO"
+	}
+	listCmd := m.folderView.list.SetItems(folderItems(m.folderView.folders, moving == nil))
+	m.folderView.list.ResetSelected()
+	spinnerCmd := m.folderView.list.StartSpinner()
+	return tea.Batch(listCmd, spinnerCmd, m.getFolders())
+}
+
+func (m *model) setFolderFilter(id string) tea.Cmd {
+	m.listView.filter.FolderId = id
+	m.setListTitle()
+	spinnerCmd := m.listView.list.StartSpinner()
+	return tea.Batch(spinnerCmd, m.getItems())
+}
+
+func (m *model) createFolder(name string) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		folder, err := m.vault.CreateFolder(ctx, name)
+		if err != nil || folder == nil {
+			return failed("Failed to create folder", err)
+		}
+		return folderSavedMsg(*folder)
+	}
+}
+
+func (m *model) renameFolder(folder bw.Folder, name string) tea.Cmd {
+	folder.Name = name
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		saved, err := m.vault.EditFolder(ctx, folder)
+		if err != nil || saved == nil {
+			return failed("Failed to rename folder", err)
+		}
+		return folderSavedMsg(*saved)
+	}
+}
+
+func (m *model) deleteFolder(folder bw.Folder) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.DeleteFolder(ctx, folder.Id)
+		if err != nil {
+			return failed("Failed to delete folder", err)
+		}
+		return folderDeletedMsg(folder)
+	}
+}
 
-	items := []list.Item{}
+// openGenerator shows the password generator. Using the generated value
+// sends it to target and goes back to the current view.
+func (m *model) openGenerator(target generatorTarget) {
+	m.generatorView.target = target
+	m.generatorView.returnTo = m.view
+	m.generatorView.generate()
+	m.view = GENERATOR
+}
 
+// useGenerated hands the generated value to wherever the generator was
+// opened from.
+func (m *model) useGenerated() tea.Cmd {
+	value := m.generatorView.value
+	m.view = m.generatorView.returnTo
+	switch m.generatorView.target {
+	case toForm:
+		m.formView.setPassword(value)
+	case toEdit:
+		m.itemView.item.SetEditValue(value)
+	default:
+		if err := m.clipboard.Copy(value); err != nil {
+			return m.listView.list.NewStatusMessage("failed to copy!")
+		}
+		return tea.Batch(m.listView.list.NewStatusMessage("copied generated password"), m.watchClipboard())
+	}
+	return nil
+}
+
+// newList creates a list styled like the main item list.
+func newList(title string) list.Model {
 	listDelegate := list.NewDefaultDelegate()
 	listDelegate.Styles.SelectedTitle.Foreground(l.Color(brightYellow))
 	listDelegate.Styles.SelectedDesc.Foreground(l.Color(dimYellow))
 	listDelegate.Styles.SelectedTitle.BorderForeground(l.Color(brightYellow))
 	listDelegate.Styles.SelectedDesc.BorderForeground(l.Color(brightYellow))
 	listDelegate.Styles.NormalTitle.Foreground(l.AdaptiveColor{Light: "#222222", Dark: "#efefef"})
-	passList := list.New(items, listDelegate, 0, 0)
-	passList.Title = "BITWARDEN"
-	passList.Styles.Title = titleStyle
+	newList := list.New([]list.Item{}, listDelegate, 0, 0)
+	newList.Title = title
+	newList.Styles.Title = titleStyle
+	newList.Styles.PaginationStyle.Foreground(l.Color("#666"))
+	newList.Paginator.Type = paginator.Arabic
+	newList.Paginator.ArabicFormat = "page %d of %d"
+	newList.Styles.FilterCursor.Foreground(l.Color(dimYellow))
+	newList.SetSpinner(spinner.MiniDot)
+	newList.StatusMessageLifetime = time.Duration(3 * time.Second)
+	return newList
+}
+
+func newModel(vault bw.Vault) model {
+	var (
+		listKeys = newListKeyMap()
+	)
+
+	passList := newList("BITWARDEN")
 	passList.AdditionalFullHelpKeys = func() []key.Binding {
 		return []key.Binding{
 			listKeys.openItem,
 			listKeys.newItem,
 			listKeys.sync,
+			listKeys.delete,
+			listKeys.purge,
+			listKeys.trash,
+			listKeys.folders,
+			listKeys.move,
+			listKeys.generator,
+			listKeys.sends,
+			listKeys.lock,
+			listKeys.settings,
+			listKeys.profiles,
 		}
 	}
-	passList.Styles.PaginationStyle.Foreground(l.Color("#666"))
-	passList.Paginator.Type = paginator.Arabic
-	passList.Paginator.ArabicFormat = "page %d of %d"
-	passList.Styles.FilterCursor.Foreground(l.Color(dimYellow))
-	passList.SetSpinner(spinner.MiniDot)
-	passList.StatusMessageLifetime = time.Duration(3 * time.Second)
+	// f opens the folders instead of paging
+	passList.KeyMap.NextPage.SetKeys("right", "l", "pgdown", "d")
 	listView := listView{
 		list: passList,
 		keys: listKeys,
 	}
 
-	inputViewInput := textinput.New()
-	inputViewInput.EchoMode = textinput.EchoPassword
-	inputViewInput.EchoCharacter = '•'
-	inputViewInput.Placeholder = "Master password"
-	inputViewInput.Prompt = "🢒 "
-	inputViewInput.PromptStyle = l.NewStyle().Foreground(l.Color(brightYellow))
-	inputViewInput.Focus()
-	inputViewSpinner := spinner.New()
-	inputViewSpinner.Spinner = spinner.MiniDot
-	inputViewSpinner.Style = l.NewStyle().Foreground(l.Color("8"))
-	inputView := inputView{
-		textInput: inputViewInput,
-		spinner:   inputViewSpinner,
-	}
-
 	itemView := itemView{}
 	itemView.item = item.New()
 	itemView.item.Styles = item.Styles{
@@ -224,17 +515,30 @@ func newModel() model {
 		Label:            itemLabelStyle,
 		SelectedProperty: selectedPropertyStyle,
 	}
+	guard := clipboard.NewGuard(clipboard.Detect(""), clipboard.DefaultTimeout)
+	itemView.item.Clipboard = guard
 
-	return model{
-		listView:  listView,
-		inputView: inputView,
-		itemView:  itemView,
-		view:      PASSINPUT,
+	m := model{
+		listView:      listView,
+		trashView:     newTrashView(),
+		folderView:    newFolderView(),
+		generatorView: newGeneratorView(),
+		sendsView:     newSendsView(),
+		sendFormView:  newSendFormView(nil),
+		profileView:   newProfileView(),
+		inputView:     newInputView(),
+		itemView:      itemView,
+		view:          PASSINPUT,
+		calls:         newCalls(),
+		lockAfter:     defaultLockAfter,
+		clipboard:     guard,
 	}
+	m.setVault(vault)
+	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return m.inputView.spinner.Tick
+	return tea.Batch(m.inputView.spinner.Tick, m.checkStatus())
 }
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -243,49 +547,111 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		finalW, finalH := msg.Width-leftGap-rightGap, msg.Height-topGap-bottomGap
 		m.listView.list.SetSize(finalW, finalH)
 		m.itemView.item.SetSize(finalW, finalH)
+		m.trashView.list.SetSize(finalW, finalH)
+		m.folderView.list.SetSize(finalW, finalH)
+		m.sendsView.list.SetSize(finalW, finalH)
+		m.profileView.list.SetSize(finalW, finalH)
+		m.width, m.height = msg.Width, msg.Height
 
 		m.itemView.item.Help.Width = msg.Width
 		m.listView.list.Help.Width = msg.Width
+		m.formView.help.Width = msg.Width
+		m.trashView.list.Help.Width = msg.Width
+		m.folderView.list.Help.Width = msg.Width
+		m.generatorView.help.Width = msg.Width
+		m.sendsView.list.Help.Width = msg.Width
+		m.sendFormView.help.Width = msg.Width
+		m.settingsView.help.Width = msg.Width
+		m.profileView.list.Help.Width = msg.Width
+	case tea.KeyMsg:
+		if m.view != PASSINPUT {
+			m.lastActivity = time.Now()
+			if key.Matches(msg, m.listView.keys.lock) {
+				return m, m.lock()
+			}
+		}
+	case idleMsg:
+		if msg.id != m.idleTimers || m.view == PASSINPUT {
+			return m, nil
+		}
+		if time.Since(m.lastActivity) >= m.lockAfter {
+			return m, m.lock()
+		}
+		return m, m.idleTimer()
+	case item.CopiedMsg:
+		return m, m.watchClipboard()
+	case clipboardTickMsg:
+		return m, m.updateClipboard(msg)
+	case item.TotpTickMsg:
+		// the countdown keeps going under the views opened from an item,
+		// like the generator, so that it still runs once the item is back
+		var itemCmd tea.Cmd
+		m.itemView.item, itemCmd = m.itemView.item.Update(msg)
+		return m, itemCmd
+	case syncTickMsg, syncedMsg, lastSyncMsg:
+		return m, m.updateSync(msg)
+	case serverChangedMsg:
+		return m, m.startOver()
+	case errorMsg:
+		m.lastError = msg.err
 	}
 
-	switch m.view {
-	case PASSINPUT:
-		{
-			switch msg := msg.(type) {
-			case tea.KeyMsg:
-				m.inputView.error = nil
-				switch msg.String() {
-				case "ctrl+c", "ctrl+d":
-					return m, tea.Quit
-				case "enter":
-					if m.view == PASSINPUT {
-						m.inputView.isLoading = true
-						return m, m.login()
-					}
-				}
-			case sessionMsg:
-				m.bwContext = msg
-				return m, m.getItems()
-			case itemsMsg:
-				items := listItemsFromBwItems(msg)
-				m.view = PASSLIST
-				m.inputView.isLoading = false
-				listCmd := m.listView.list.SetItems(items)
-				return m, listCmd
-			case errorMsg:
-				m.inputView.textInput.SetValue("")
-				m.inputView.error = msg.err
-				m.inputView.isLoading = false
-				m.listView.list.StopSpinner()
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case m.errorDetails != nil:
+			m.errorDetails = nil
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		case msg.String() == errorDetailsKey && hasDetails(m.lastError):
+			m.errorDetails = m.lastError
+			return m, nil
+		case msg.String() == "esc" && m.prompt == nil && m.confirm == nil && m.waiting():
+			// the call fails with context.Canceled, which stops the spinner
+			m.calls.cancelIn(m.view)
+			return m, nil
+		}
+	}
+
+	if m.prompt != nil {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc":
+				m.prompt = nil
 				return m, nil
+			case "enter":
+				prompt := m.prompt
+				m.prompt = nil
+				return m, prompt.onSubmit(prompt.input.Value())
 			}
-			var (
-				inputCmd, spinnerCmd tea.Cmd
-			)
-			m.inputView.spinner, spinnerCmd = m.inputView.spinner.Update(msg)
-			m.inputView.textInput, inputCmd = m.inputView.textInput.Update(msg)
-			return m, tea.Batch(inputCmd, spinnerCmd)
+			var promptCmd tea.Cmd
+			m.prompt.input, promptCmd = m.prompt.input.Update(msg)
+			return m, promptCmd
 		}
+	}
+
+	if m.confirm != nil {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			confirm := m.confirm
+			m.confirm = nil
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "y", "Y":
+				return m, confirm.onYes()
+			}
+			return m, confirm.onNo
+		}
+	}
+
+	switch m.view {
+	case PASSINPUT:
+		return m.updateAuth(msg)
+	case SETTINGS:
+		return m.updateSettings(msg)
 	case PASSLIST:
 		{
 			switch msg := msg.(type) {
@@ -298,25 +664,69 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					spinnerCmd := m.listView.list.StartSpinner()
 					return m, tea.Batch(spinnerCmd, m.getItem())
 				case key.Matches(msg, m.listView.keys.newItem):
-					cmd := m.listView.list.NewStatusMessage("new item!")
-					return m, cmd
+					m.view = PASSNEW
+					m.formView = newFormView()
+					m.formView.help.Width = m.listView.list.Help.Width
+					return m, tea.Batch(textinput.Blink, m.getFolders())
+				case key.Matches(msg, m.listView.keys.delete), key.Matches(msg, m.listView.keys.purge):
+					i, ok := m.listView.list.SelectedItem().(listItem)
+					if !ok {
+						break
+					}
+					item := bw.Item{Id: i.Id(), Name: i.Title()}
+					return m, m.confirmDelete(item, key.Matches(msg, m.listView.keys.purge))
+				case key.Matches(msg, m.listView.keys.folders):
+					return m, m.openFolders(nil)
+				case key.Matches(msg, m.listView.keys.move):
+					i, ok := m.listView.list.SelectedItem().(listItem)
+					if !ok {
+						break
+					}
+					return m, m.openFolders(&bw.Item{Id: i.Id(), Name: i.Title()})
+				case key.Matches(msg, m.listView.keys.generator):
+					m.openGenerator(toClipboard)
+					return m, nil
+				case key.Matches(msg, m.listView.keys.sends):
+					return m, m.openSends()
+				case key.Matches(msg, m.listView.keys.settings):
+					return m, m.openSettings()
+				case key.Matches(msg, m.listView.keys.profiles) && m.profileView.open != nil:
+					return m, m.openProfiles()
+				case key.Matches(msg, m.listView.keys.trash):
+					m.view = TRASHLIST
+					spinnerCmd := m.trashView.list.StartSpinner()
+					return m, tea.Batch(spinnerCmd, m.getTrash())
 				case key.Matches(msg, m.listView.keys.sync):
+					if m.syncing {
+						return m, m.listView.list.NewStatusMessage("already syncing")
+					}
 					spinnerCmd := m.listView.list.StartSpinner()
-					statusCmd := m.listView.list.NewStatusMessage("started syncing")
-					return m, tea.Batch(spinnerCmd, statusCmd, m.getItems())
+					statusCmd := m.listView.list.NewStatusMessage("syncing…")
+					return m, tea.Batch(spinnerCmd, statusCmd, m.sync(false))
 				}
 			case itemsMsg:
 				items := listItemsFromBwItems(msg)
 				listCmd := m.listView.list.SetItems(items)
 				m.listView.list.StopSpinner()
+				m.listView.selectPending()
 				return m, listCmd
+			case list.FilterMatchesMsg:
+				var listCmd tea.Cmd
+				m.listView.list, listCmd = m.listView.list.Update(msg)
+				selectItem(&m.listView.list, m.listView.selectId)
+				m.listView.selectId = ""
+				return m, listCmd
+			case itemDeletedMsg:
+				statusCmd := m.listView.list.NewStatusMessage(deletedStatus(msg))
+				return m, tea.Batch(statusCmd, m.getItems())
 			case itemMsg:
 				m.view = PASSITEM
 				m.listView.list.StopSpinner()
-				m.itemView.item.Item = bw.Item(msg)
-				return m, nil
+				itemCmd := m.itemView.item.SetItem(bw.Item(msg))
+				return m, itemCmd
 			case errorMsg:
-				statusCmd := m.listView.list.NewStatusMessage(msg.err.Error())
+				m.listView.list.StopSpinner()
+				statusCmd := m.listView.list.NewStatusMessage(errorText(msg.err))
 				return m, statusCmd
 			}
 			var listCmd tea.Cmd
@@ -328,14 +738,351 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch msg := msg.(type) {
 			case tea.KeyMsg:
 				switch {
+				case m.itemView.item.Editing():
 				case key.Matches(msg, m.itemView.item.KeyMap.Back):
 					m.view = PASSLIST
 				}
+			case item.SaveMsg:
+				statusCmd := m.itemView.item.NewStatusMessage("saving…")
+				return m, tea.Batch(statusCmd, m.editItem(msg.Item))
+			case itemEditedMsg:
+				m.itemView.item.UpdateItem(bw.Item(msg))
+				statusCmd := m.itemView.item.NewStatusMessage("saved")
+				return m, tea.Batch(statusCmd, m.getItems())
+			case item.DeleteMsg:
+				return m, m.confirmDelete(msg.Item, msg.Permanent)
+			case item.GenerateMsg:
+				m.openGenerator(toEdit)
+				return m, nil
+			case item.SendMsg:
+				return m, m.openSendForm(newPasswordSend(msg.Item.Name+" password", msg.Item.Login.Password))
+			case item.MoveMsg:
+				moving := msg.Item
+				return m, m.openFolders(&moving)
+			case item.DownloadMsg, overwriteMsg, attachmentSavedMsg,
+				item.AttachMsg, item.DeleteAttachmentMsg, attachmentsChangedMsg:
+				return m, m.updateAttachments(msg)
+			case itemDeletedMsg:
+				m.view = PASSLIST
+				statusCmd := m.listView.list.NewStatusMessage(deletedStatus(msg))
+				return m, tea.Batch(statusCmd, m.getItems())
+			case itemsMsg:
+				listCmd := m.listView.list.SetItems(listItemsFromBwItems(msg))
+				m.listView.list.StopSpinner()
+				return m, listCmd
+			case errorMsg:
+				m.listView.list.StopSpinner()
+				statusCmd := m.itemView.item.NewStatusMessage(errorText(msg.err))
+				return m, statusCmd
 			}
 			var itemCmd tea.Cmd
 			m.itemView.item, itemCmd = m.itemView.item.Update(msg)
 			return m, itemCmd
 		}
+	case PASSNEW:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.formView.isSaving {
+					break
+				}
+				switch {
+				case msg.String() == "ctrl+c":
+					return m, tea.Quit
+				case key.Matches(msg, m.formView.keys.cancel):
+					m.view = PASSLIST
+					return m, nil
+				case key.Matches(msg, m.formView.keys.generate):
+					m.openGenerator(toForm)
+					return m, nil
+				case key.Matches(msg, m.formView.keys.save):
+					item, err := m.formView.item()
+					if err != nil {
+						m.formView.error = err
+						return m, nil
+					}
+					m.formView.isSaving = true
+					return m, tea.Batch(m.formView.spinner.Tick, m.createItem(item))
+				}
+			case foldersMsg:
+				m.formView.folders = msg
+				return m, nil
+			case itemCreatedMsg:
+				m.view = PASSLIST
+				m.formView.isSaving = false
+				m.listView.selectId = msg.Id
+				spinnerCmd := m.listView.list.StartSpinner()
+				statusCmd := m.listView.list.NewStatusMessage("created " + msg.Name)
+				return m, tea.Batch(spinnerCmd, statusCmd, m.getItems())
+			case errorMsg:
+				m.formView.isSaving = false
+				m.formView.error = msg.err
+				return m, nil
+			}
+			var formCmd tea.Cmd
+			m.formView, formCmd = m.formView.Update(msg)
+			return m, formCmd
+		}
+	case TRASHLIST:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.trashView.list.FilterState() == list.Filtering {
+					break
+				}
+				i, hasSelection := m.trashView.list.SelectedItem().(listItem)
+				selected := bw.Item{Id: i.Id(), Name: i.Title()}
+				switch {
+				case key.Matches(msg, m.trashView.keys.back) && m.trashView.list.FilterState() == list.Unfiltered:
+					m.view = PASSLIST
+					return m, nil
+				case key.Matches(msg, m.trashView.keys.restore) && hasSelection:
+					return m, m.restoreItem(selected)
+				case key.Matches(msg, m.trashView.keys.purge) && hasSelection:
+					return m, m.confirmDelete(selected, true)
+				}
+			case trashMsg:
+				listCmd := m.trashView.list.SetItems(trashItemsFromBwItems(msg))
+				m.trashView.list.StopSpinner()
+				return m, listCmd
+			case itemRestoredMsg:
+				statusCmd := m.trashView.list.NewStatusMessage("restored " + msg.Name)
+				return m, tea.Batch(statusCmd, m.getTrash(), m.getItems())
+			case itemDeletedMsg:
+				statusCmd := m.trashView.list.NewStatusMessage(deletedStatus(msg))
+				return m, tea.Batch(statusCmd, m.getTrash())
+			case itemsMsg:
+				listCmd := m.listView.list.SetItems(listItemsFromBwItems(msg))
+				return m, listCmd
+			case errorMsg:
+				m.trashView.list.StopSpinner()
+				statusCmd := m.trashView.list.NewStatusMessage(errorText(msg.err))
+				return m, statusCmd
+			}
+			var listCmd tea.Cmd
+			m.trashView.list, listCmd = m.trashView.list.Update(msg)
+			return m, listCmd
+		}
+	case GENERATOR:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				switch {
+				case msg.String() == "ctrl+c":
+					return m, tea.Quit
+				case key.Matches(msg, m.generatorView.keys.back):
+					m.view = m.generatorView.returnTo
+					return m, nil
+				case key.Matches(msg, m.generatorView.keys.use) && m.generatorView.error == nil:
+					return m, m.useGenerated()
+				}
+			}
+			var generatorCmd tea.Cmd
+			m.generatorView, generatorCmd = m.generatorView.Update(msg)
+			return m, generatorCmd
+		}
+	case SENDLIST:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.sendsView.list.FilterState() == list.Filtering {
+					break
+				}
+				send, hasSelection := m.sendsView.selected()
+				switch {
+				case key.Matches(msg, m.sendsView.keys.back) && m.sendsView.list.FilterState() == list.Unfiltered:
+					m.view = PASSLIST
+					return m, nil
+				case key.Matches(msg, m.sendsView.keys.create):
+					return m, m.openSendForm(newSendFormView(nil))
+				case key.Matches(msg, m.sendsView.keys.edit) && hasSelection:
+					return m, m.openSendForm(newSendFormView(&send))
+				case key.Matches(msg, m.sendsView.keys.copyUrl) && hasSelection:
+					status, copyCmd := m.copySendUrl(send)
+					return m, tea.Batch(m.sendsView.list.NewStatusMessage(status), copyCmd)
+				case key.Matches(msg, m.sendsView.keys.delete) && hasSelection:
+					m.confirm = &confirmation{
+						prompt: "Delete send " + send.Name + "? Its link stops working.",
+						onYes: func() tea.Cmd {
+							return m.deleteSend(send)
+						},
+					}
+					return m, nil
+				}
+			case sendsMsg:
+				m.sendsView.sends = msg
+				listCmd := m.sendsView.list.SetItems(sendItems(msg))
+				m.sendsView.list.StopSpinner()
+				return m, listCmd
+			case sendDeletedMsg:
+				statusCmd := m.sendsView.list.NewStatusMessage("deleted send " + msg.Name)
+				return m, tea.Batch(statusCmd, m.getSends())
+			case errorMsg:
+				m.sendsView.list.StopSpinner()
+				statusCmd := m.sendsView.list.NewStatusMessage(errorText(msg.err))
+				return m, statusCmd
+			}
+			var listCmd tea.Cmd
+			m.sendsView.list, listCmd = m.sendsView.list.Update(msg)
+			return m, listCmd
+		}
+	case SENDFORM:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.sendFormView.isSaving {
+					break
+				}
+				switch {
+				case msg.String() == "ctrl+c":
+					return m, tea.Quit
+				case key.Matches(msg, m.sendFormView.keys.cancel):
+					m.view = m.sendFormView.returnTo
+					return m, nil
+				case key.Matches(msg, m.sendFormView.keys.save):
+					send, err := m.sendFormView.send()
+					if err != nil {
+						m.sendFormView.error = err
+						return m, nil
+					}
+					m.sendFormView.isSaving = true
+					return m, tea.Batch(m.sendFormView.spinner.Tick, m.saveSend(send))
+				}
+			case sendSavedMsg:
+				m.view = m.sendFormView.returnTo
+				m.sendFormView.isSaving = false
+				send := bw.Send(msg)
+				if m.view == PASSITEM {
+					// a send made from an item is meant to be shared right away
+					status, copyCmd := m.copySendUrl(send)
+					return m, tea.Batch(m.itemView.item.NewStatusMessage(status), copyCmd)
+				}
+				statusCmd := m.sendsView.list.NewStatusMessage("saved send " + send.Name)
+				return m, tea.Batch(statusCmd, m.getSends())
+			case errorMsg:
+				m.sendFormView.isSaving = false
+				m.sendFormView.error = msg.err
+				return m, nil
+			}
+			var formCmd tea.Cmd
+			m.sendFormView, formCmd = m.sendFormView.Update(msg)
+			return m, formCmd
+		}
+	case PROFILELIST:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.profileView.list.FilterState() == list.Filtering {
+					break
+				}
+				switch {
+				case key.Matches(msg, m.profileView.keys.back) && m.profileView.list.FilterState() == list.Unfiltered:
+					m.view = m.profileView.returnTo
+					return m, nil
+				case key.Matches(msg, m.profileView.keys.choose):
+					i, ok := m.profileView.list.SelectedItem().(listItem)
+					if !ok {
+						break
+					}
+					return m, m.switchProfile(i.Id())
+				case key.Matches(msg, m.profileView.keys.create):
+					m.prompt = newTextPrompt("New profile name", "", m.createProfile)
+					return m, nil
+				}
+			case profilesMsg:
+				listCmd := m.profileView.list.SetItems(profileItems(msg, m.profileView.current))
+				m.profileView.list.StopSpinner()
+				selectItem(&m.profileView.list, m.profileView.current)
+				return m, listCmd
+			case profileCreatedMsg:
+				return m, m.switchProfile(string(msg))
+			case errorMsg:
+				m.profileView.list.StopSpinner()
+				statusCmd := m.profileView.list.NewStatusMessage(errorText(msg.err))
+				return m, statusCmd
+			}
+			var listCmd tea.Cmd
+			m.profileView.list, listCmd = m.profileView.list.Update(msg)
+			return m, listCmd
+		}
+	case FOLDERLIST:
+		{
+			switch msg := msg.(type) {
+			case tea.KeyMsg:
+				if m.folderView.list.FilterState() == list.Filtering {
+					break
+				}
+				folder, isFolder := m.folderView.folder()
+				switch {
+				case key.Matches(msg, m.folderView.keys.back) && m.folderView.list.FilterState() == list.Unfiltered:
+					m.view = m.folderView.returnTo
+					return m, nil
+				case key.Matches(msg, m.folderView.keys.choose):
+					i, ok := m.folderView.list.SelectedItem().(listItem)
+					if !ok {
+						break
+					}
+					if m.folderView.moving != nil {
+						return m, m.moveItem(*m.folderView.moving, i.Id())
+					}
+					m.view = PASSLIST
+					return m, m.setFolderFilter(i.Id())
+				case key.Matches(msg, m.folderView.keys.create):
+					m.prompt = newTextPrompt("New folder name (use Parent/Child to nest)", "", m.createFolder)
+					return m, nil
+				case key.Matches(msg, m.folderView.keys.rename) && isFolder:
+					m.prompt = newTextPrompt("Rename folder", folder.Name, func(name string) tea.Cmd {
+						return m.renameFolder(folder, name)
+					})
+					return m, nil
+				case key.Matches(msg, m.folderView.keys.delete) && isFolder:
+					m.confirm = &confirmation{
+						prompt: "Delete folder " + folder.Name + "? Its items are kept outside any folder.",
+						onYes: func() tea.Cmd {
+							return m.deleteFolder(folder)
+						},
+					}
+					return m, nil
+				}
+			case foldersMsg:
+				m.folderView.folders = msg
+				listCmd := m.folderView.list.SetItems(folderItems(msg, m.folderView.moving == nil))
+				m.folderView.list.StopSpinner()
+				return m, listCmd
+			case folderSavedMsg:
+				statusCmd := m.folderView.list.NewStatusMessage("saved folder " + msg.Name)
+				return m, tea.Batch(statusCmd, m.getFolders())
+			case folderDeletedMsg:
+				statusCmd := m.folderView.list.NewStatusMessage("deleted folder " + msg.Name)
+				var filterCmd tea.Cmd
+				if m.listView.filter.FolderId == msg.Id {
+					filterCmd = m.setFolderFilter(allFolders)
+				}
+				return m, tea.Batch(statusCmd, filterCmd, m.getFolders())
+			case itemMovedMsg:
+				m.view = m.folderView.returnTo
+				m.folderView.moving = nil
+				status := "moved " + msg.Name + " to " + folderName(m.folderView.folders, msg.FolderId)
+				if msg.FolderId == "" {
+					status = "moved " + msg.Name + " out of its folder"
+				}
+				var statusCmd tea.Cmd
+				if m.view == PASSITEM {
+					m.itemView.item.Item = bw.Item(msg)
+					statusCmd = m.itemView.item.NewStatusMessage(status)
+				} else {
+					statusCmd = m.listView.list.NewStatusMessage(status)
+				}
+				return m, tea.Batch(statusCmd, m.getItems())
+			case errorMsg:
+				m.folderView.list.StopSpinner()
+				statusCmd := m.folderView.list.NewStatusMessage(errorText(msg.err))
+				return m, statusCmd
+			}
+			var listCmd tea.Cmd
+			m.folderView.list, listCmd = m.folderView.list.Update(msg)
+			return m, listCmd
+		}
 	}
 
 	return m, nil
@@ -343,21 +1090,6 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // == VIEW ==
 
-func renderInput(m model) string {
-	var b strings.Builder
-	if m.inputView.isLoading {
-		b.WriteString(m.inputView.spinner.View() + " ")
-		titleStyle.MarginLeft(0)
-	} else {
-		titleStyle.MarginLeft(2)
-	}
-	b.WriteString(titleStyle.Render("BITWARDEN"))
-	b.WriteString("\n\n" + m.inputView.textInput.View())
-	if m.inputView.error != nil {
-		b.WriteString("\n\n" + l.NewStyle().Foreground(l.Color("9")).Render(m.inputView.error.Error()))
-	}
-	return appStyle.Render(b.String())
-}
 func renderList(m model) string {
 	out := m.listView.list.View()
 	return appStyle.Render(out)
@@ -366,8 +1098,51 @@ func renderItem(m model) string {
 	out := m.itemView.item.View()
 	return appStyle.Render(out)
 }
+func renderForm(m model) string {
+	out := m.formView.View()
+	return appStyle.Render(out)
+}
+
+func renderTrash(m model) string {
+	out := m.trashView.list.View()
+	return appStyle.Render(out)
+}
+
+func renderGenerator(m model) string {
+	out := m.generatorView.View()
+	return appStyle.Render(out)
+}
+
+func renderSends(m model) string {
+	out := m.sendsView.list.View()
+	return appStyle.Render(out)
+}
+
+func renderSendForm(m model) string {
+	out := m.sendFormView.View()
+	return appStyle.Render(out)
+}
+
+func renderProfiles(m model) string {
+	out := m.profileView.list.View()
+	return appStyle.Render(out)
+}
+
+func renderFolders(m model) string {
+	out := m.folderView.list.View()
+	return appStyle.Render(out)
+}
 
 func (m model) View() string {
+	if m.errorDetails != nil {
+		return renderErrorDetails(m)
+	}
+	if m.confirm != nil {
+		return renderConfirmation(m)
+	}
+	if m.prompt != nil {
+		return renderPrompt(m)
+	}
 	switch m.view {
 	case PASSINPUT:
 		return renderInput(m)
@@ -375,6 +1150,22 @@ func (m model) View() string {
 		return renderList(m)
 	case PASSITEM:
 		return renderItem(m)
+	case PASSNEW:
+		return renderForm(m)
+	case TRASHLIST:
+		return renderTrash(m)
+	case FOLDERLIST:
+		return renderFolders(m)
+	case GENERATOR:
+		return renderGenerator(m)
+	case SENDLIST:
+		return renderSends(m)
+	case SENDFORM:
+		return renderSendForm(m)
+	case SETTINGS:
+		return renderSettings(m)
+	case PROFILELIST:
+		return renderProfiles(m)
 	}
 	return "why am i here?"
 }
@@ -382,12 +1173,140 @@ func (m model) View() string {
 // == MAIN ==
 
 func main() {
-	if err := tea.NewProgram(newModel(), tea.WithAltScreen()).Start(); err != nil {
+	session := flag.String("session", "", "session key of an unlocked vault, as printed by bw unlock --raw")
+	lockAfter := flag.Duration("lock-after", defaultLockAfter, "lock the vault after this long without a key press, 0 to never lock")
+	clearAfter := flag.Duration("clear-clipboard", clipboard.DefaultTimeout, "take copied secrets out of the clipboard after this long, 0 to leave them")
+	clipboardName := flag.String("clipboard", os.Getenv("BWTUI_CLIPBOARD"), "clipboard to use: auto, osc52, wl-copy, xclip, xsel, system or command")
+	clipboardCommand := flag.String("clipboard-command", os.Getenv("BWTUI_CLIPBOARD_COMMAND"), "command to copy with, reading the text from stdin")
+	backend := flag.String("backend", envOr("BWTUI_BACKEND", "cli"), "how to reach the vault: cli runs bw, api talks to the server itself, serve talks to bw serve")
+	serveAddr := flag.String("serve", os.Getenv("BWTUI_SERVE"), "where bw serve listens for the serve backend, host:port or unix:path, a socket only you can reach by default; started there unless running already")
+	server := flag.String("server", os.Getenv("BWTUI_SERVER"), "server to use, as bw config server sets it; switching servers logs out")
+	timeout := flag.Duration("timeout", defaultTimeout, "give up on a bw command after this long, 0 to wait forever")
+	syncTimeout := flag.Duration("sync-timeout", defaultSyncTimeout, "give up on syncing after this long, 0 to wait forever")
+	syncEvery := flag.Duration("sync-every", 0, "sync the vault in the background this often, 0 to only sync on request")
+	cache := flag.Bool("cache", true, "keep an encrypted copy of the items, to show them right away and offline")
+	profile := flag.String("profile", envOr("BWTUI_PROFILE", defaultProfile), "profile to start with, each has its own account, server and session")
+	flag.Parse()
+
+	key, err := sessionKey(*session)
+	if err != nil {
+		fmt.Println("Error reading the session:", err)
+		os.Exit(1)
+	}
+	vault, err := newVault(*backend, *profile, key, *serveAddr, *cache)
+	if err != nil {
+		fmt.Println("Error setting up the vault:", err)
+		os.Exit(1)
+	}
+	m := newModel(vault)
+	m.wantServer = *server
+	m.profileView.current = *profile
+	m.profileView.open = func(name string) (bw.Vault, error) {
+		return newVault(*backend, name, "", *serveAddr, *cache)
+	}
+	m.lockAfter = *lockAfter
+	m.syncEvery = *syncEvery
+	m.calls.timeout = *timeout
+	m.calls.syncTimeout = *syncTimeout
+	m.clipboard.Timeout = *clearAfter
+	m.clipboard.Backend, err = clipboard.New(*clipboardName, *clipboardCommand)
+	if err != nil {
+		fmt.Println("Error setting up the clipboard:", err)
+		os.Exit(1)
+	}
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).StartReturningModel()
+	// don't leave a secret behind in the clipboard
+	m.clipboard.Clear()
+	// nor a bw serve with the vault unlocked, of whichever profile was last
+	if final, ok := final.(model); ok {
+		closeVault(final.vault)
+	} else {
+		closeVault(m.vault)
+	}
+	if err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
 }
 
+// newVault sets up the vault of a profile with the backend named by the
+// --backend flag, starting with session when it isn't empty.
+func newVault(backend, profile, session, serveAddr string, cache bool) (bw.Vault, error) {
+	dir, err := profileDir(profile)
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("creating profile: %w", err)
+		}
+	}
+	var vault bw.Vault
+	switch backend {
+	case "api":
+		if session != "" {
+			// the api backend keeps its own login, a bw session key doesn't open it
+			return nil, errors.New("--session and BW_SESSION_FILE only work with the cli and serve backends")
+		}
+		api := bw.NewAPI("")
+		if dir != "" {
+			api.StatePath = filepath.Join(dir, "api.json")
+		}
+		vault = api
+	case "cli":
+		cli := bw.NewContext()
+		if dir != "" {
+			// BW_SESSION is for bw's own data directory
+			cli.AppDataDir = dir
+			cli.SessionKey = ""
+		}
+		if session != "" {
+			cli.SetSession(session)
+		}
+		vault = cli
+	case "serve":
+		if dir != "" {
+			// a bw serve only serves one data directory
+			serveAddr = "unix:" + filepath.Join(dir, "serve.sock")
+		} else if serveAddr == "" {
+			serveAddr = bw.DefaultServeAddr()
+			if serveAddr == "" {
+				return nil, errors.New("no directory for the socket of bw serve, set one with --serve")
+			}
+		}
+		serve := bw.NewServe(serveAddr)
+		if dir != "" {
+			serve.AppDataDir = dir
+			serve.SessionKey = ""
+		}
+		if session != "" {
+			serve.SessionKey = session
+		}
+		vault = serve
+	default:
+		return nil, errors.New("unknown backend " + backend)
+	}
+	if path := profileCachePath(profile); cache && path != "" {
+		vault = bw.NewCache(vault, path)
+	}
+	return vault, nil
+}
+
+// closeVault lets go of what vault holds on to, like the bw serve it
+// started.
+func closeVault(vault bw.Vault) {
+	if closer, ok := vault.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+func envOr(name, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return value
+}
+
 // == UTILS ==
 
 func listItemsFromBwItems(bwItems []bw.Item) []list.Item {
@@ -396,9 +1315,75 @@ func listItemsFromBwItems(bwItems []bw.Item) []list.Item {
 		i := listItem{
 			id:          pass.Id,
 			title:       pass.Name,
-			description: pass.Login.Username,
+			description: describeItem(pass),
 		}
 		items = append(items, i)
 	}
 	return items
 }
+
+// trashItemsFromBwItems lists deleted items along with when they were
+// deleted.
+func trashItemsFromBwItems(bwItems []bw.Item) []list.Item {
+	var items []list.Item
+	for _, pass := range bwItems {
+		description := "deleted"
+		if pass.DeletedDate != nil {
+			description += " " + pass.DeletedDate.Local().Format("2006-01-02 15:04")
+		}
+		items = append(items, listItem{
+			id:          pass.Id,
+			title:       pass.Name,
+			description: description,
+		})
+	}
+	return items
+}
+
+func deletedStatus(msg itemDeletedMsg) string {
+	if msg.permanent {
+		return "deleted " + msg.item.Name
+	}
+	return "moved " + msg.item.Name + " to trash"
+}
+
+// selectPending selects the item selectId names. A filtered list only shows
+// it once the filter matches are in, so it stays pending until then.
+func (v *listView) selectPending() {
+	if selectItem(&v.list, v.selectId) || v.list.FilterState() == list.Unfiltered {
+		v.selectId = ""
+	}
+}
+
+// selectItem moves the list cursor onto the item with the given id.
+func selectItem(itemList *list.Model, id string) bool {
+	if id == "" {
+		return false
+	}
+	for i, li := range itemList.VisibleItems() {
+		if li.(listItem).Id() == id {
+			itemList.Select(i)
+			return true
+		}
+	}
+	return false
+}
+
+// describeItem picks the line shown under an item's name in the list.
+func describeItem(i bw.Item) string {
+	switch i.Type {
+	case bw.TypeLogin:
+		return i.Login.Username
+	case bw.TypeCard:
+		desc := i.Card.Brand
+		if n := len(i.Card.Number); n >= 4 {
+			desc = strings.TrimSpace(desc + " *" + i.Card.Number[n-4:])
+		}
+		return desc
+	case bw.TypeIdentity:
+		return i.Identity.FullName()
+	case bw.TypeSecureNote:
+		return "Secure note"
+	}
+	return ""
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+
+	bw "bitwarden-tui/internal"
+)
+
+// fakeClipboard is a clipboard that only holds a string.
+type fakeClipboard struct{ text string }
+
+func (c *fakeClipboard) Name() string            { return "fake" }
+func (c *fakeClipboard) Read() (string, error)   { return c.text, nil }
+func (c *fakeClipboard) Write(text string) error { c.text = text; return nil }
+
+// newTestModel is a model of a locked vault in memory, unlocked with
+// "hunter2".
+func newTestModel(t *testing.T) (model, *bw.Memory, *fakeClipboard) {
+	t.Helper()
+	vault := bw.NewMemory("hunter2", []bw.Item{
+		{
+			Id:    "i1",
+			Type:  bw.TypeLogin,
+			Name:  "GitHub",
+			Login: bw.Login{Username: "octo", Password: "p4ssw0rd"},
+		},
+		{
+			Id:    "i2",
+			Type:  bw.TypeSecureNote,
+			Name:  "Recovery codes",
+			Notes: "1234 5678",
+		},
+	}, nil)
+	vault.Email = "user@example.com"
+	board := &fakeClipboard{text: "before"}
+	m := newModel(vault)
+	m.clipboard.Backend = board
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = drain(t, m, m.Init())
+	return m, vault, board
+}
+
+// update hands msg to m, then carries out the commands that follow until
+// none are left.
+func update(t *testing.T, m model, msg tea.Msg) model {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	return drain(t, next.(model), cmd)
+}
+
+// drain runs cmd and the commands its messages lead to, one at a time.
+// Commands still waiting after a moment, like the ticks of spinners and
+// timers, are dropped.
+func drain(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	queue := []tea.Cmd{cmd}
+	for steps := 0; len(queue) > 0; steps++ {
+		if steps > 1000 {
+			t.Fatal("the model never settled")
+		}
+		cmd, queue = queue[0], queue[1:]
+		if cmd == nil {
+			continue
+		}
+		done := make(chan tea.Msg, 1)
+		go func(cmd tea.Cmd) { done <- cmd() }(cmd)
+		var msg tea.Msg
+		select {
+		case msg = <-done:
+		case <-time.After(50 * time.Millisecond):
+			continue
+		}
+		switch msg.(type) {
+		case nil, spinner.TickMsg:
+			continue
+		}
+		// tea.Batch hides its commands in a slice of them
+		if batch := reflect.ValueOf(msg); batch.Kind() == reflect.Slice && batch.Type().Elem() == reflect.TypeOf(cmd) {
+			for i := 0; i < batch.Len(); i++ {
+				queue = append(queue, batch.Index(i).Interface().(tea.Cmd))
+			}
+			continue
+		}
+		next, cmd := m.Update(msg)
+		m = next.(model)
+		queue = append(queue, cmd)
+	}
+	return m
+}
+
+func typeText(t *testing.T, m model, s string) model {
+	t.Helper()
+	return update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func press(t *testing.T, m model, key tea.KeyType) model {
+	t.Helper()
+	return update(t, m, tea.KeyMsg{Type: key})
+}
+
+// unlock unlocks the vault of newTestModel.
+func unlock(t *testing.T, m model) model {
+	t.Helper()
+	m = typeText(t, m, "hunter2")
+	m = press(t, m, tea.KeyEnter)
+	if m.view == PASSINPUT {
+		t.Fatalf("still locked after unlocking: %v", m.inputView.error)
+	}
+	return m
+}
+
+func TestUnlockLoadsList(t *testing.T) {
+	m, _, _ := newTestModel(t)
+	if m.view != PASSINPUT || m.inputView.state != authUnlock {
+		t.Fatalf("view %v in state %v, want the unlock screen", m.view, m.inputView.state)
+	}
+
+	m = typeText(t, m, "wrong")
+	m = press(t, m, tea.KeyEnter)
+	if m.view != PASSINPUT || m.inputView.error == nil || m.inputView.error.Error() != "Invalid master password!" {
+		t.Fatalf("view %v with error %v after a wrong password", m.view, m.inputView.error)
+	}
+
+	m.inputView.textInput.SetValue("")
+	m = unlock(t, m)
+	if m.view != PASSLIST {
+		t.Fatalf("view %v after unlocking, want the list", m.view)
+	}
+	items := m.listView.list.Items()
+	if len(items) != 2 {
+		t.Fatalf("list has %d items, want 2", len(items))
+	}
+	if got := items[0].(listItem).title; got != "GitHub" {
+		t.Errorf("first item is %q, want GitHub", got)
+	}
+}
+
+func TestOpenAndCopyItem(t *testing.T) {
+	m, _, board := newTestModel(t)
+	m = unlock(t, m)
+
+	m = press(t, m, tea.KeyEnter)
+	if m.view != PASSITEM || m.itemView.item.Item.Name != "GitHub" {
+		t.Fatalf("view %v showing %q, want GitHub open", m.view, m.itemView.item.Item.Name)
+	}
+	m = typeText(t, m, "j")
+	m = typeText(t, m, "c")
+	if board.text != "p4ssw0rd" {
+		t.Errorf("clipboard = %q after copying the password", board.text)
+	}
+	if !m.clipboard.Pending() {
+		t.Error("the copied password isn't waiting to be cleared")
+	}
+
+	m = press(t, m, tea.KeyEsc)
+	if m.view != PASSLIST {
+		t.Errorf("view %v after esc, want the list", m.view)
+	}
+}
+
+func TestTotpTicksUnderOtherViews(t *testing.T) {
+	m, vault, _ := newTestModel(t)
+	vault.Items[0].Login.Totp = "JBSWY3DPEHPK3PXP"
+	m = unlock(t, m)
+	m = press(t, m, tea.KeyEnter)
+	if m.view != PASSITEM {
+		t.Fatalf("view %v, want the item open", m.view)
+	}
+	// the tick the item is waiting for, which drain drops
+	tick := m.itemView.item.SetItem(m.itemView.item.Item)()
+
+	m = typeText(t, m, "m")
+	if m.view != FOLDERLIST {
+		t.Fatalf("view %v after m, want the folders", m.view)
+	}
+	if _, cmd := m.Update(tick); cmd == nil {
+		t.Error("the countdown stopped under the folder list")
+	}
+}
+
+func TestLockWipes(t *testing.T) {
+	m, vault, board := newTestModel(t)
+	m = unlock(t, m)
+	m = press(t, m, tea.KeyEnter)
+	m = typeText(t, m, "j")
+	m = typeText(t, m, "c")
+
+	m = press(t, m, tea.KeyCtrlL)
+	if m.view != PASSINPUT || m.inputView.state != authUnlock {
+		t.Fatalf("view %v in state %v after locking, want the unlock screen", m.view, m.inputView.state)
+	}
+	if len(m.listView.list.Items()) != 0 || m.itemView.item.Item.Id != "" {
+		t.Error("items are still there after locking")
+	}
+	if board.text != "before" {
+		t.Errorf("clipboard = %q after locking, want what was there before the copy", board.text)
+	}
+	if status, _ := vault.Status(context.Background()); status.Status != bw.StatusLocked {
+		t.Errorf("vault is %s after locking", status.Status)
+	}
+
+	// unlocking goes back to the item that was open
+	m = unlock(t, m)
+	if m.view != PASSITEM || m.itemView.item.Item.Id != "i1" {
+		t.Errorf("view %v showing %q after unlocking again, want GitHub open", m.view, m.itemView.item.Item.Id)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Default time limits of backend calls. Syncing downloads the whole vault,
+// so it gets longer.
+const (
+	defaultTimeout     = time.Minute
+	defaultSyncTimeout = 5 * time.Minute
+)
+
+// calls keeps track of the backend calls in flight, so that esc can cancel
+// them while a spinner runs. Copies of the model share it, the commands
+// Init returns included.
+type calls struct {
+	timeout     time.Duration
+	syncTimeout time.Duration
+
+	// ctx is the parent of the calls esc cancels, backgroundCtx of the
+	// ones only locking does
+	ctx              context.Context
+	cancel           context.CancelFunc
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
+	active           []call
+}
+
+// call is a backend call in flight, with the view that waits on it.
+type call struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	view   view
+}
+
+func newCalls() *calls {
+	c := &calls{
+		timeout:     defaultTimeout,
+		syncTimeout: defaultSyncTimeout,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.backgroundCtx, c.cancelBackground = context.WithCancel(context.Background())
+	return c
+}
+
+// start is the context of a backend call that view v waits on, ending
+// after the timeout or when the calls of v are cancelled. The call must be
+// done with it, which is what tells runningIn that it returned.
+func (c *calls) start(v view) (context.Context, context.CancelFunc) {
+	return c.startWithin(v, c.timeout)
+}
+
+func (c *calls) startSync(v view) (context.Context, context.CancelFunc) {
+	return c.startWithin(v, c.syncTimeout)
+}
+
+// startWithin starts a call limited to timeout, 0 for none.
+func (c *calls) startWithin(v view, timeout time.Duration) (ctx context.Context, done context.CancelFunc) {
+	if timeout > 0 {
+		ctx, done = context.WithTimeout(c.ctx, timeout)
+	} else {
+		ctx, done = context.WithCancel(c.ctx)
+	}
+	running := c.active[:0]
+	for _, active := range c.active {
+		if active.ctx.Err() == nil {
+			running = append(running, active)
+		}
+	}
+	c.active = append(running, call{ctx: ctx, cancel: done, view: v})
+	return ctx, done
+}
+
+// detached is the context of a call that esc doesn't cancel, still limited
+// to the timeout.
+func (c *calls) detached() (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(context.Background(), c.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// background is the context of a call the user isn't waiting on. esc
+// leaves it be, but locking still cancels it.
+func (c *calls) background(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(c.backgroundCtx, timeout)
+	}
+	return context.WithCancel(c.backgroundCtx)
+}
+
+// runningIn tells whether a call view v waits on hasn't returned yet.
+func (c *calls) runningIn(v view) bool {
+	for _, active := range c.active {
+		if active.view == v && active.ctx.Err() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelIn ends the calls view v waits on, killing the bw processes behind
+// them. The calls of other views and background ones carry on.
+func (c *calls) cancelIn(v view) {
+	running := c.active[:0]
+	for _, active := range c.active {
+		if active.view == v {
+			active.cancel()
+		} else if active.ctx.Err() == nil {
+			running = append(running, active)
+		}
+	}
+	c.active = running
+}
+
+// cancelAll ends every call in flight, background ones included, as
+// locking does.
+func (c *calls) cancelAll() {
+	c.cancel()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.active = nil
+	c.cancelBackground()
+	c.backgroundCtx, c.cancelBackground = context.WithCancel(context.Background())
+}
+
+// waiting tells whether the view showing runs a spinner for a call of its
+// own, which esc then cancels rather than going to the view.
+func (m model) waiting() bool {
+	switch m.view {
+	case PASSINPUT:
+		if !m.inputView.isLoading {
+			return false
+		}
+	case PASSNEW:
+		if !m.formView.isSaving {
+			return false
+		}
+	case SENDFORM:
+		if !m.sendFormView.isSaving {
+			return false
+		}
+	case SETTINGS:
+		if !m.settingsView.isLoading {
+			return false
+		}
+	case PASSITEM, GENERATOR:
+		// their calls report in status messages, esc stays with the view
+		return false
+	}
+	return m.calls.runningIn(m.view)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type clipboardTickMsg struct {
+	// id tells apart the ticks started by earlier copies
+	id int
+}
+
+// watchClipboard counts down to clearing a copy once a second. Copying
+// again starts over with a new chain of ticks.
+func (m *model) watchClipboard() tea.Cmd {
+	m.clipboardTicks++
+	return m.clipboardTick()
+}
+
+func (m *model) clipboardTick() tea.Cmd {
+	if !m.clipboard.Pending() {
+		return nil
+	}
+	id := m.clipboardTicks
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return clipboardTickMsg{id: id}
+	})
+}
+
+// updateClipboard clears the copy once it is due, and otherwise shows the
+// time left on the list. The item view shows it by itself.
+func (m *model) updateClipboard(msg clipboardTickMsg) tea.Cmd {
+	if msg.id != m.clipboardTicks || !m.clipboard.Pending() {
+		return nil
+	}
+	remaining := m.clipboard.Remaining(time.Now())
+	if remaining > 0 {
+		var statusCmd tea.Cmd
+		if m.view == PASSLIST {
+			statusCmd = m.listView.list.NewStatusMessage("clipboard clears in " + remaining.Round(time.Second).String())
+		}
+		return tea.Batch(statusCmd, m.clipboardTick())
+	}
+	status := "clipboard cleared"
+	if err := m.clipboard.Clear(); err != nil {
+		status = "failed to clear clipboard!"
+	}
+	if m.view == PASSITEM {
+		return m.itemView.item.NewStatusMessage(status)
+	}
+	return m.listView.list.NewStatusMessage(status)
+}
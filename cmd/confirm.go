@@ -0,0 +1,29 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+)
+
+var confirmStyle = l.NewStyle().
+	Border(l.RoundedBorder()).
+	BorderForeground(l.Color(brightYellow)).
+	Padding(1, 2)
+
+// confirmation is a yes/no question shown in place of the current view.
+// While it is open every key press answers it; anything but y is a no.
+// onYes makes its command once the answer is in, so that the call it
+// starts isn't running while the question is asked.
+type confirmation struct {
+	prompt string
+	onYes  func() tea.Cmd
+	// onNo, when set, runs on any other answer
+	onNo tea.Cmd
+}
+
+func renderConfirmation(m model) string {
+	dialog := confirmStyle.Render(
+		m.confirm.prompt + "\n\n" + itemLabelStyle.Render("y to confirm, any other key to cancel"),
+	)
+	return l.Place(m.width, m.height, l.Center, l.Center, dialog)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	l "github.com/charmbracelet/lipgloss"
+
+	bw "bitwarden-tui/internal"
+)
+
+// errorDetailsKey opens what bw printed for the latest error. Being a
+// control key it works in text inputs too.
+const errorDetailsKey = "ctrl+o"
+
+var errorDetailsStyle = confirmStyle.Copy().BorderForeground(l.Color("9"))
+
+// errCancelled is what a call esc cancelled fails with.
+var errCancelled = errors.New("Cancelled")
+
+// failed reports that action didn't work, and why when the vault told.
+func failed(action string, err error) errorMsg {
+	switch {
+	case err == nil:
+		return errorMsg{errors.New(action + "!")}
+	case errors.Is(err, context.Canceled):
+		return errorMsg{errCancelled}
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorMsg{errors.New(action + ": timed out")}
+	}
+	return errorMsg{fmt.Errorf("%s: %w", action, err)}
+}
+
+// hasDetails tells whether err came with bw output worth a look.
+func hasDetails(err error) bool {
+	var cmdErr *bw.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Stderr != ""
+}
+
+// errorText is err as shown in status lines, pointing to the details.
+func errorText(err error) string {
+	if hasDetails(err) {
+		return err.Error() + " (" + errorDetailsKey + " for details)"
+	}
+	return err.Error()
+}
+
+// renderErrorDetails shows what bw printed for m.errorDetails, which only
+// opens for errors hasDetails is true of.
+func renderErrorDetails(m model) string {
+	var cmdErr *bw.CommandError
+	if !errors.As(m.errorDetails, &cmdErr) {
+		return m.errorDetails.Error()
+	}
+	var b strings.Builder
+	b.WriteString(l.NewStyle().Foreground(l.Color("9")).Render(m.errorDetails.Error()))
+	b.WriteString("\n\n" + itemLabelStyle.Render(
+		"bw "+cmdErr.Command+" exited with status "+strconv.Itoa(cmdErr.ExitCode)))
+	b.WriteString("\n\n" + cmdErr.Stderr)
+	b.WriteString("\n\n" + itemLabelStyle.Render("any key to close"))
+	dialog := errorDetailsStyle.Copy().MaxWidth(m.width).Width(m.width * 3 / 4).Render(b.String())
+	return l.Place(m.width, m.height, l.Center, l.Center, dialog)
+}
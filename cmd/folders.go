@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+
+	bw "bitwarden-tui/internal"
+)
+
+const (
+	// allFolders and noFolder are the folder list entries that aren't
+	// folders. noFolder doubles as bw's --folderid for unfiled items.
+	allFolders = ""
+	noFolder   = "null"
+)
+
+type folderKeyMap struct {
+	choose key.Binding
+	create key.Binding
+	rename key.Binding
+	delete key.Binding
+	back   key.Binding
+}
+
+func newFolderKeyMap() *folderKeyMap {
+	return &folderKeyMap{
+		choose: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "choose folder"),
+		),
+		create: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "new folder"),
+		),
+		rename: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "rename folder"),
+		),
+		delete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "delete folder"),
+		),
+		back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "go back"),
+		),
+	}
+}
+
+// folderView lists the vault's folders. It either picks the folder the item
+// list is filtered by, or, when moving is set, the folder to move it into.
+type folderView struct {
+	list    list.Model
+	keys    *folderKeyMap
+	folders []bw.Folder
+	// moving is the item being moved, nil when browsing
+	moving *bw.Item
+	// returnTo is the view to go back to
+	returnTo view
+}
+
+func newFolderView() folderView {
+	keys := newFolderKeyMap()
+	folderList := newList("FOLDERS")
+	folderList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.choose, keys.create, keys.back}
+	}
+	folderList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.choose, keys.create, keys.rename, keys.delete, keys.back}
+	}
+	folderList.KeyMap.Quit = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
+	return folderView{
+		list: folderList,
+		keys: keys,
+	}
+}
+
+// folder returns the folder the selected entry stands for, if it is one.
+func (f *folderView) folder() (bw.Folder, bool) {
+	i, ok := f.list.SelectedItem().(listItem)
+	if !ok {
+		return bw.Folder{}, false
+	}
+	for _, folder := range f.folders {
+		if folder.Id == i.Id() {
+			return folder, true
+		}
+	}
+	return bw.Folder{}, false
+}
+
+// folderItems lists folders nested by their "Parent/Child" names. A child
+// whose parent doesn't exist keeps its full name at the top level.
+func folderItems(folders []bw.Folder, browsing bool) []list.Item {
+	sorted := append([]bw.Folder(nil), folders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+	names := make(map[string]bool, len(sorted))
+	for _, f := range sorted {
+		names[f.Name] = true
+	}
+	// parents sort before their children, so their depth is always known
+	depths := make(map[string]int, len(sorted))
+
+	var items []list.Item
+	if browsing {
+		items = append(items, listItem{id: allFolders, title: "All items", description: "every folder"})
+	}
+	items = append(items, listItem{id: noFolder, title: "No folder", description: "items outside any folder"})
+	for _, f := range sorted {
+		depth, label := 0, f.Name
+		parts := strings.Split(f.Name, "/")
+		for i := len(parts) - 1; i > 0; i-- {
+			if parent := strings.Join(parts[:i], "/"); names[parent] {
+				depth = depths[parent] + 1
+				label = strings.Join(parts[i:], "/")
+				break
+			}
+		}
+		depths[f.Name] = depth
+		items = append(items, listItem{
+			id:          f.Id,
+			title:       strings.Repeat("  ", depth) + label,
+			description: strings.Repeat("  ", depth) + f.Name,
+		})
+	}
+	return items
+}
+
+// folderName is how a folder filter is shown in the item list's title.
+func folderName(folders []bw.Folder, id string) string {
+	switch id {
+	case allFolders:
+		return ""
+	case noFolder:
+		return "No folder"
+	}
+	for _, f := range folders {
+		if f.Id == id {
+			return f.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+
+	bw "bitwarden-tui/internal"
+)
+
+type formRow int
+
+const (
+	nameRow formRow = iota
+	usernameRow
+	passwordRow
+	uriRow
+	fieldRow
+	notesRow
+)
+
+var formLabels = map[formRow]string{
+	nameRow:     "Name",
+	usernameRow: "Username",
+	passwordRow: "Password",
+	uriRow:      "URI",
+	fieldRow:    "Field",
+	notesRow:    "Notes",
+}
+
+const folderLabel = "Folder"
+
+type formKeyMap struct {
+	next       key.Binding
+	prev       key.Binding
+	addRow     key.Binding
+	prevFolder key.Binding
+	nextFolder key.Binding
+	generate   key.Binding
+	save       key.Binding
+	cancel     key.Binding
+}
+
+func newFormKeyMap() *formKeyMap {
+	return &formKeyMap{
+		next: key.NewBinding(
+			key.WithKeys("tab", "down"),
+			key.WithHelp("tab/↓", "next"),
+		),
+		prev: key.NewBinding(
+			key.WithKeys("shift+tab", "up"),
+			key.WithHelp("shift+tab/↑", "previous"),
+		),
+		addRow: key.NewBinding(
+			key.WithKeys("ctrl+n"),
+			key.WithHelp("ctrl+n", "another uri/field"),
+		),
+		prevFolder: key.NewBinding(
+			key.WithKeys("left"),
+			key.WithHelp("←", "previous folder"),
+		),
+		nextFolder: key.NewBinding(
+			key.WithKeys("right"),
+			key.WithHelp("→", "next folder"),
+		),
+		generate: key.NewBinding(
+			key.WithKeys("ctrl+g"),
+			key.WithHelp("ctrl+g", "generate password"),
+		),
+		save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
+
+func (k formKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.next, k.prev, k.addRow, k.generate, k.save, k.cancel}
+}
+
+func (k formKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.next, k.prev},
+		{k.addRow, k.prevFolder, k.nextFolder, k.generate},
+		{k.save, k.cancel},
+	}
+}
+
+type formInput struct {
+	row   formRow
+	input textinput.Model
+}
+
+// formView collects a new login item. Every input is a row; URI and custom
+// field rows can be repeated. The folder picker sits after the last input.
+type formView struct {
+	inputs   []formInput
+	focus    int
+	folders  []bw.Folder
+	folder   int // index into folders, -1 for no folder
+	spinner  spinner.Model
+	help     help.Model
+	keys     *formKeyMap
+	isSaving bool
+	error    error
+}
+
+func newFormInput(row formRow) formInput {
+	input := textinput.New()
+	input.Prompt = ""
+	switch row {
+	case passwordRow:
+		input.EchoMode = textinput.EchoPassword
+		input.EchoCharacter = '•'
+	case uriRow:
+		input.Placeholder = "https://example.com"
+	case fieldRow:
+		input.Placeholder = "name=value"
+	}
+	return formInput{row: row, input: input}
+}
+
+func newFormView() formView {
+	s := spinner.New()
+	s.Spinner = spinner.MiniDot
+	s.Style = l.NewStyle().Foreground(l.Color("8"))
+	f := formView{
+		folder:  -1,
+		spinner: s,
+		help:    help.New(),
+		keys:    newFormKeyMap(),
+	}
+	for _, row := range []formRow{nameRow, usernameRow, passwordRow, uriRow, fieldRow, notesRow} {
+		f.inputs = append(f.inputs, newFormInput(row))
+	}
+	f.inputs[0].input.Focus()
+	return f
+}
+
+func (f *formView) onFolderRow() bool {
+	return f.focus == len(f.inputs)
+}
+
+func (f *formView) setFocus(i int) tea.Cmd {
+	count := len(f.inputs) + 1
+	f.focus = (i + count) % count
+	var cmd tea.Cmd
+	for i := range f.inputs {
+		if i == f.focus {
+			cmd = f.inputs[i].input.Focus()
+		} else {
+			f.inputs[i].input.Blur()
+		}
+	}
+	return cmd
+}
+
+// addRow repeats the focused URI or field row right below it.
+func (f *formView) addRow() tea.Cmd {
+	if f.onFolderRow() {
+		return nil
+	}
+	row := f.inputs[f.focus].row
+	if row != uriRow && row != fieldRow {
+		return nil
+	}
+	at := f.focus + 1
+	f.inputs = append(f.inputs[:at], append([]formInput{newFormInput(row)}, f.inputs[at:]...)...)
+	return f.setFocus(at)
+}
+
+// setPassword fills the password row, used for generated passwords.
+func (f *formView) setPassword(password string) {
+	for i := range f.inputs {
+		if f.inputs[i].row == passwordRow {
+			f.inputs[i].input.SetValue(password)
+			f.inputs[i].input.CursorEnd()
+			return
+		}
+	}
+}
+
+func (f *formView) cycleFolder(delta int) {
+	count := len(f.folders) + 1
+	f.folder = (f.folder+1+delta+count)%count - 1
+}
+
+func (f *formView) folderName() string {
+	if f.folder < 0 || f.folder >= len(f.folders) {
+		return "No folder"
+	}
+	return f.folders[f.folder].Name
+}
+
+// item builds the login item described by the form.
+func (f *formView) item() (bw.Item, error) {
+	item := bw.Item{Type: bw.TypeLogin}
+	for _, in := range f.inputs {
+		value := in.input.Value()
+		switch in.row {
+		case nameRow:
+			item.Name = strings.TrimSpace(value)
+		case usernameRow:
+			item.Login.Username = strings.TrimSpace(value)
+		case passwordRow:
+			item.Login.Password = value
+		case uriRow:
+			if uri := strings.TrimSpace(value); uri != "" {
+				item.Login.Uris = append(item.Login.Uris, bw.Uri{Uri: uri})
+			}
+		case fieldRow:
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			name, fieldValue := value, ""
+			if i := strings.Index(value, "="); i >= 0 {
+				name, fieldValue = value[:i], value[i+1:]
+			}
+			item.Fields = append(item.Fields, bw.Field{Name: strings.TrimSpace(name), Value: fieldValue})
+		case notesRow:
+			item.Notes = value
+		}
+	}
+	if f.folder >= 0 && f.folder < len(f.folders) {
+		item.FolderId = f.folders[f.folder].Id
+	}
+	if item.Name == "" {
+		return item, errors.New("Name is required!")
+	}
+	return item, nil
+}
+
+func (f formView) Update(msg tea.Msg) (formView, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		f.error = nil
+		switch {
+		case key.Matches(msg, f.keys.next):
+			return f, f.setFocus(f.focus + 1)
+		case key.Matches(msg, f.keys.prev):
+			return f, f.setFocus(f.focus - 1)
+		case key.Matches(msg, f.keys.addRow):
+			return f, f.addRow()
+		case f.onFolderRow() && key.Matches(msg, f.keys.prevFolder):
+			f.cycleFolder(-1)
+			return f, nil
+		case f.onFolderRow() && key.Matches(msg, f.keys.nextFolder):
+			f.cycleFolder(1)
+			return f, nil
+		}
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		f.spinner, cmd = f.spinner.Update(msg)
+		return f, cmd
+	}
+	if f.onFolderRow() {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.inputs[f.focus].input, cmd = f.inputs[f.focus].input.Update(msg)
+	return f, cmd
+}
+
+func (f formView) View() string {
+	maxLabelChars := len(folderLabel)
+	for _, label := range formLabels {
+		if len(label) > maxLabelChars {
+			maxLabelChars = len(label)
+		}
+	}
+	renderLabel := func(label string, focused bool) string {
+		padded := label + strings.Repeat(" ", maxLabelChars-len(label))
+		if focused {
+			return selectedPropertyStyle.Render("🢒 ") + itemLabelStyle.Render(padded)
+		}
+		return "  " + itemLabelStyle.Render(padded)
+	}
+
+	var b strings.Builder
+	if f.isSaving {
+		b.WriteString(f.spinner.View() + " ")
+	} else {
+		b.WriteString("  ")
+	}
+	b.WriteString(titleStyle.Copy().MarginLeft(0).Render("NEW ITEM"))
+	b.WriteString("\n")
+	for i, in := range f.inputs {
+		b.WriteString("\n" + renderLabel(formLabels[in.row], i == f.focus) + in.input.View())
+	}
+	folder := "‹ " + f.folderName() + " ›"
+	if f.onFolderRow() {
+		folder = selectedPropertyStyle.Render(folder)
+	}
+	b.WriteString("\n" + renderLabel(folderLabel, f.onFolderRow()) + folder)
+	if f.error != nil {
+		b.WriteString("\n\n  " + l.NewStyle().Foreground(l.Color("9")).Render(errorText(f.error)))
+	}
+	b.WriteString("\n\n  " + f.help.View(f.keys))
+	return b.String()
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+
+	"bitwarden-tui/internal/generator"
+)
+
+var separators = []string{"-", " ", ".", "_", ",", ""}
+
+type generatorKeyMap struct {
+	up         key.Binding
+	down       key.Binding
+	decrease   key.Binding
+	increase   key.Binding
+	toggle     key.Binding
+	regenerate key.Binding
+	use        key.Binding
+	back       key.Binding
+}
+
+func newGeneratorKeyMap() *generatorKeyMap {
+	return &generatorKeyMap{
+		up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "move up"),
+		),
+		down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "move down"),
+		),
+		decrease: key.NewBinding(
+			key.WithKeys("left", "h", "-"),
+			key.WithHelp("←/h", "decrease"),
+		),
+		increase: key.NewBinding(
+			key.WithKeys("right", "l", "+"),
+			key.WithHelp("→/l", "increase"),
+		),
+		toggle: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "toggle"),
+		),
+		regenerate: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "regenerate"),
+		),
+		use: key.NewBinding(
+			key.WithKeys("enter", "c"),
+			key.WithHelp("enter/c", "use"),
+		),
+		back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "go back"),
+		),
+	}
+}
+
+func (k generatorKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.up, k.down, k.decrease, k.increase, k.regenerate, k.use, k.back}
+}
+
+func (k generatorKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.up, k.down},
+		{k.decrease, k.increase, k.toggle},
+		{k.regenerate, k.use, k.back},
+	}
+}
+
+// generatorTarget is where a generated value goes when it is used.
+type generatorTarget int
+
+const (
+	// toClipboard copies the value, for the generator opened from the list
+	toClipboard generatorTarget = iota
+	// toForm fills the password of the new item form
+	toForm
+	// toEdit fills the property being edited in the item view
+	toEdit
+)
+
+// generatorOption is one adjustable row of the generator. adjust gets -1
+// or 1 for left and right; booleans flip either way.
+type generatorOption struct {
+	label  string
+	value  func(g *generatorView) string
+	adjust func(g *generatorView, delta int)
+}
+
+type generatorView struct {
+	passphrase bool
+	password   generator.PasswordOptions
+	phrase     generator.PassphraseOptions
+	separator  int // index into separators
+
+	cursor   int
+	value    string
+	error    error
+	help     help.Model
+	keys     *generatorKeyMap
+	target   generatorTarget
+	returnTo view
+}
+
+func newGeneratorView() generatorView {
+	g := generatorView{
+		password: generator.DefaultPasswordOptions(),
+		phrase:   generator.DefaultPassphraseOptions(),
+		help:     help.New(),
+		keys:     newGeneratorKeyMap(),
+	}
+	g.generate()
+	return g
+}
+
+func toggleOption(label string, field func(g *generatorView) *bool) generatorOption {
+	return generatorOption{
+		label: label,
+		value: func(g *generatorView) string {
+			if *field(g) {
+				return "[x]"
+			}
+			return "[ ]"
+		},
+		adjust: func(g *generatorView, delta int) {
+			*field(g) = !*field(g)
+		},
+	}
+}
+
+func numberOption(label string, min, max int, field func(g *generatorView) *int) generatorOption {
+	return generatorOption{
+		label: label,
+		value: func(g *generatorView) string {
+			return "‹ " + strconv.Itoa(*field(g)) + " ›"
+		},
+		adjust: func(g *generatorView, delta int) {
+			v := *field(g) + delta
+			if v >= min && v <= max {
+				*field(g) = v
+			}
+		},
+	}
+}
+
+func (g *generatorView) options() []generatorOption {
+	mode := generatorOption{
+		label: "Type",
+		value: func(g *generatorView) string {
+			if g.passphrase {
+				return "‹ passphrase ›"
+			}
+			return "‹ password ›"
+		},
+		adjust: func(g *generatorView, delta int) {
+			g.passphrase = !g.passphrase
+		},
+	}
+	if g.passphrase {
+		return []generatorOption{
+			mode,
+			numberOption("Words", 3, 20, func(g *generatorView) *int { return &g.phrase.Words }),
+			{
+				label: "Separator",
+				value: func(g *generatorView) string {
+					return "‹ " + strconv.Quote(g.phrase.Separator) + " ›"
+				},
+				adjust: func(g *generatorView, delta int) {
+					g.separator = (g.separator + delta + len(separators)) % len(separators)
+					g.phrase.Separator = separators[g.separator]
+				},
+			},
+			toggleOption("Capitalize", func(g *generatorView) *bool { return &g.phrase.Capitalize }),
+			toggleOption("Include number", func(g *generatorView) *bool { return &g.phrase.IncludeNumber }),
+		}
+	}
+	return []generatorOption{
+		mode,
+		numberOption("Length", 5, 128, func(g *generatorView) *int { return &g.password.Length }),
+		toggleOption("A-Z", func(g *generatorView) *bool { return &g.password.Uppercase }),
+		toggleOption("a-z", func(g *generatorView) *bool { return &g.password.Lowercase }),
+		toggleOption("0-9", func(g *generatorView) *bool { return &g.password.Digits }),
+		toggleOption("!@#$%^&*", func(g *generatorView) *bool { return &g.password.Special }),
+		toggleOption("Avoid ambiguous", func(g *generatorView) *bool { return &g.password.AvoidAmbiguous }),
+		numberOption("Min numbers", 0, 9, func(g *generatorView) *int { return &g.password.MinDigits }),
+		numberOption("Min special", 0, 9, func(g *generatorView) *int { return &g.password.MinSpecial }),
+	}
+}
+
+func (g *generatorView) generate() {
+	if g.passphrase {
+		g.value, g.error = generator.Passphrase(g.phrase)
+	} else {
+		g.value, g.error = generator.Password(g.password)
+	}
+}
+
+func (g *generatorView) entropy() float64 {
+	if g.passphrase {
+		return generator.PassphraseEntropy(g.phrase)
+	}
+	return generator.PasswordEntropy(g.password)
+}
+
+func (g generatorView) Update(msg tea.Msg) (generatorView, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return g, nil
+	}
+	options := g.options()
+	switch {
+	case key.Matches(keyMsg, g.keys.up):
+		g.cursor = (g.cursor - 1 + len(options)) % len(options)
+	case key.Matches(keyMsg, g.keys.down):
+		g.cursor = (g.cursor + 1) % len(options)
+	case key.Matches(keyMsg, g.keys.decrease):
+		options[g.cursor].adjust(&g, -1)
+		g.generate()
+	case key.Matches(keyMsg, g.keys.increase), key.Matches(keyMsg, g.keys.toggle):
+		options[g.cursor].adjust(&g, 1)
+		g.generate()
+	case key.Matches(keyMsg, g.keys.regenerate):
+		g.generate()
+	}
+	// switching between modes changes the number of rows
+	if count := len(g.options()); g.cursor >= count {
+		g.cursor = count - 1
+	}
+	return g, nil
+}
+
+func (g generatorView) View() string {
+	options := g.options()
+	maxLabelChars := 0
+	for _, o := range options {
+		if len(o.label) > maxLabelChars {
+			maxLabelChars = len(o.label)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("  " + titleStyle.Copy().MarginLeft(0).Render("GENERATOR") + "\n\n")
+	if g.error != nil {
+		b.WriteString("  " + l.NewStyle().Foreground(l.Color("9")).Render(g.error.Error()))
+	} else {
+		b.WriteString("  " + selectedPropertyStyle.Render(g.value))
+	}
+	b.WriteString("\n  " + itemLabelStyle.Render(fmt.Sprintf("~%.0f bits of entropy", g.entropy())) + "\n")
+	for i, o := range options {
+		label := o.label + strings.Repeat(" ", maxLabelChars-len(o.label))
+		if i == g.cursor {
+			b.WriteString("\n" + selectedPropertyStyle.Render("🢒 ") + itemLabelStyle.Render(label) + selectedPropertyStyle.Render(o.value(&g)))
+		} else {
+			b.WriteString("\n  " + itemLabelStyle.Render(label) + o.value(&g))
+		}
+	}
+	b.WriteString("\n\n  " + g.help.View(g.keys))
+	return b.String()
+}
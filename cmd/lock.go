@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultLockAfter is how long the vault stays unlocked without a key press.
+const defaultLockAfter = 15 * time.Minute
+
+// resumePoint is where the user was when the vault locked, to go back to
+// after unlocking. The list keeps its own filter while its items are gone.
+type resumePoint struct {
+	selectId string
+	itemId   string
+}
+
+type idleMsg struct {
+	// id tells apart the idle timers of successive unlocks
+	id int
+}
+
+// idleTimer fires once the vault may have been idle for lockAfter. Key
+// presses in between push the deadline back rather than start new timers.
+func (m *model) idleTimer() tea.Cmd {
+	if m.lockAfter <= 0 {
+		return nil
+	}
+	id := m.idleTimers
+	wait := m.lockAfter - time.Since(m.lastActivity)
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return idleMsg{id: id}
+	})
+}
+
+// startIdleTimer starts counting idle time, once the vault is unlocked.
+func (m *model) startIdleTimer() tea.Cmd {
+	m.idleTimers++
+	m.syncTimers++
+	m.lastActivity = time.Now()
+	return m.idleTimer()
+}
+
+// lock wipes everything read from the vault, then locks it and shows the
+// unlock screen.
+func (m *model) lock() tea.Cmd {
+	m.resume = resumePoint{}
+	if i, ok := m.listView.list.SelectedItem().(listItem); ok {
+		m.resume.selectId = i.Id()
+	}
+	if m.view == PASSITEM {
+		m.resume.itemId = m.itemView.item.Item.Id
+		m.resume.selectId = m.resume.itemId
+	}
+
+	m.wipe()
+
+	m.view = PASSINPUT
+	m.inputView.error = nil
+	m.inputView.notice = ""
+	focusCmd := m.inputView.setState(authUnlock)
+	ctx, done := m.calls.detached()
+	return tea.Batch(focusCmd, func() tea.Msg {
+		defer done()
+		if err := m.vault.Lock(ctx); err != nil {
+			return failed("Failed to lock the vault", err)
+		}
+		return nil
+	})
+}
+
+// wipe forgets everything read from the vault and stops whatever was
+// still going on with it.
+func (m *model) wipe() {
+	m.listView.list.SetItems([]list.Item{})
+	m.listView.list.StopSpinner()
+	m.itemView.item.Reset()
+	m.trashView.list.SetItems([]list.Item{})
+	m.folderView.folders = nil
+	m.folderView.list.SetItems([]list.Item{})
+	m.folderView.moving = nil
+	m.formView = newFormView()
+	m.generatorView.value = ""
+	m.sendsView.sends = nil
+	m.sendsView.list.SetItems([]list.Item{})
+	m.sendFormView = newSendFormView(nil)
+	m.confirm = nil
+	m.prompt = nil
+	// nothing still loading may show up once locked
+	m.calls.cancelAll()
+	m.idleTimers++
+	m.syncTimers++
+	m.clipboard.Clear()
+}
+
+// resumeAfterUnlock goes back to the item the user was looking at when the
+// vault locked.
+func (m *model) resumeAfterUnlock() tea.Cmd {
+	resume := m.resume
+	m.resume = resumePoint{}
+	m.listView.selectId = resume.selectId
+	if resume.itemId == "" {
+		return nil
+	}
+	return m.fetchItem(resume.itemId)
+}
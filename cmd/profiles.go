@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	bw "bitwarden-tui/internal"
+)
+
+// defaultProfile is the account bw itself knows about, kept in bw's own
+// data directory. Other profiles each get a directory of their own, so
+// their login, server and session stay apart.
+const defaultProfile = "default"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type profileKeyMap struct {
+	choose key.Binding
+	create key.Binding
+	back   key.Binding
+}
+
+func newProfileKeyMap() *profileKeyMap {
+	return &profileKeyMap{
+		choose: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "switch profile"),
+		),
+		create: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "new profile"),
+		),
+		back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "go back"),
+		),
+	}
+}
+
+// profileView lists the profiles to switch to.
+type profileView struct {
+	list list.Model
+	keys *profileKeyMap
+	// current is the profile in use
+	current string
+	// open sets up the vault of a profile
+	open func(name string) (bw.Vault, error)
+	// returnTo is the view to go back to
+	returnTo view
+}
+
+type profilesMsg []string
+type profileCreatedMsg string
+
+func newProfileView() profileView {
+	keys := newProfileKeyMap()
+	profileList := newList("PROFILES")
+	profileList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.choose, keys.create, keys.back}
+	}
+	profileList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.choose, keys.create, keys.back}
+	}
+	profileList.KeyMap.Quit = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
+	return profileView{
+		list:    profileList,
+		keys:    keys,
+		current: defaultProfile,
+	}
+}
+
+// profileDir is where a profile keeps its bw data, "" for the default one.
+func profileDir(name string) (string, error) {
+	if name == defaultProfile {
+		return "", nil
+	}
+	if !profileNamePattern.MatchString(name) {
+		return "", errors.New("invalid profile name " + name)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bwtui", "profiles", name), nil
+}
+
+// profileCachePath is the file the offline cache of a profile goes in.
+func profileCachePath(name string) string {
+	if name == defaultProfile {
+		return bw.DefaultCachePath()
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bwtui", "profiles", name, "vault.cache")
+}
+
+// profileNames lists the default profile, then the others by name.
+func profileNames() ([]string, error) {
+	names := []string{defaultProfile}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return names, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "bwtui", "profiles"))
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	} else if err != nil {
+		return names, err
+	}
+	var others []string
+	for _, entry := range entries {
+		if entry.IsDir() && profileNamePattern.MatchString(entry.Name()) && entry.Name() != defaultProfile {
+			others = append(others, entry.Name())
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...), nil
+}
+
+// == CMD ==
+
+func (m *model) getProfiles() tea.Cmd {
+	return func() tea.Msg {
+		names, err := profileNames()
+		if err != nil {
+			return failed("Failed to list profiles", err)
+		}
+		return profilesMsg(names)
+	}
+}
+
+// openProfiles shows the profile switcher, going back to the current view
+// when done.
+func (m *model) openProfiles() tea.Cmd {
+	m.profileView.returnTo = m.view
+	m.view = PROFILELIST
+	spinnerCmd := m.profileView.list.StartSpinner()
+	return tea.Batch(spinnerCmd, m.getProfiles())
+}
+
+// switchProfile locks the vault of the current profile, wipes what was
+// read from it and moves on to the vault of another.
+func (m *model) switchProfile(name string) tea.Cmd {
+	if name == m.profileView.current {
+		m.view = m.profileView.returnTo
+		return nil
+	}
+	vault, err := m.profileView.open(name)
+	if err != nil {
+		return func() tea.Msg { return failed("Failed to open profile "+name, err) }
+	}
+	old := m.vault
+	ctx, done := m.calls.detached()
+	lockCmd := func() tea.Msg {
+		defer done()
+		// the old vault's status isn't known, a locked one stays as it is
+		_ = old.Lock(ctx)
+		closeVault(old)
+		return nil
+	}
+	m.setVault(vault)
+	m.profileView.current = name
+	return tea.Batch(lockCmd, m.startOver())
+}
+
+// createProfile makes a new profile, to switch to once it exists.
+func (m *model) createProfile(name string) tea.Cmd {
+	return func() tea.Msg {
+		if name == defaultProfile {
+			return profileCreatedMsg(name)
+		}
+		dir, err := profileDir(name)
+		if err != nil {
+			return failed("Failed to create profile", err)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return failed("Failed to create profile", err)
+		}
+		return profileCreatedMsg(name)
+	}
+}
+
+// setVault makes the model use vault, through the cache it may have.
+func (m *model) setVault(vault bw.Vault) {
+	m.vault = vault
+	m.cache, _ = vault.(*bw.Cache)
+}
+
+// == UTILS ==
+
+func profileItems(names []string, current string) []list.Item {
+	var items []list.Item
+	for _, name := range names {
+		description := "bw's own account"
+		if name != defaultProfile {
+			description = "separate account"
+		}
+		if name == current {
+			description += " · in use"
+		}
+		items = append(items, listItem{id: name, title: name, description: description})
+	}
+	return items
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+)
+
+// textPrompt asks for a single line of text in place of the current view.
+// enter submits the value, esc cancels.
+type textPrompt struct {
+	title    string
+	input    textinput.Model
+	onSubmit func(value string) tea.Cmd
+}
+
+func newTextPrompt(title, value string, onSubmit func(value string) tea.Cmd) *textPrompt {
+	input := textinput.New()
+	input.Prompt = "🢒 "
+	input.PromptStyle = l.NewStyle().Foreground(l.Color(brightYellow))
+	input.SetValue(value)
+	// the prompt only sees key presses, so it can't blink
+	input.SetCursorMode(textinput.CursorStatic)
+	input.Focus()
+	return &textPrompt{
+		title:    title,
+		input:    input,
+		onSubmit: onSubmit,
+	}
+}
+
+func renderPrompt(m model) string {
+	dialog := confirmStyle.Render(
+		m.prompt.title + "\n\n" + m.prompt.input.View() + "\n\n" +
+			itemLabelStyle.Render("enter to confirm, esc to cancel"),
+	)
+	return l.Place(m.width, m.height, l.Center, l.Center, dialog)
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+
+	bw "bitwarden-tui/internal"
+)
+
+type sendRow int
+
+const (
+	sendNameRow sendRow = iota
+	sendTypeRow
+	sendTextRow
+	sendFileRow
+	sendHiddenRow
+	sendDeletionRow
+	sendExpirationRow
+	sendMaxAccessRow
+	sendPasswordRow
+	sendNotesRow
+	sendHideEmailRow
+)
+
+var sendLabels = map[sendRow]string{
+	sendNameRow:       "Name",
+	sendTypeRow:       "Type",
+	sendTextRow:       "Text",
+	sendFileRow:       "File",
+	sendHiddenRow:     "Hide text",
+	sendDeletionRow:   "Deleted after",
+	sendExpirationRow: "Expires after",
+	sendMaxAccessRow:  "Max views",
+	sendPasswordRow:   "Password",
+	sendNotesRow:      "Notes",
+	sendHideEmailRow:  "Hide my email",
+}
+
+type sendDuration struct {
+	name     string
+	duration time.Duration
+}
+
+const day = 24 * time.Hour
+
+var (
+	sendDeletions = []sendDuration{
+		{"1 hour", time.Hour}, {"1 day", day}, {"2 days", 2 * day}, {"3 days", 3 * day},
+		{"7 days", 7 * day}, {"14 days", 14 * day}, {"30 days", 30 * day},
+	}
+	// a zero duration never expires
+	sendExpirations = []sendDuration{
+		{"never", 0}, {"1 hour", time.Hour}, {"1 day", day}, {"2 days", 2 * day},
+		{"3 days", 3 * day}, {"7 days", 7 * day},
+	}
+)
+
+const defaultSendDeletion = 4 // 7 days
+
+// keepDate is the choice index standing for the date an edited send has.
+const keepDate = -1
+
+type sendFormKeyMap struct {
+	next     key.Binding
+	prev     key.Binding
+	previous key.Binding
+	forward  key.Binding
+	toggle   key.Binding
+	save     key.Binding
+	cancel   key.Binding
+}
+
+func newSendFormKeyMap() *sendFormKeyMap {
+	return &sendFormKeyMap{
+		next: key.NewBinding(
+			key.WithKeys("tab", "down"),
+			key.WithHelp("tab/↓", "next"),
+		),
+		prev: key.NewBinding(
+			key.WithKeys("shift+tab", "up"),
+			key.WithHelp("shift+tab/↑", "previous"),
+		),
+		previous: key.NewBinding(
+			key.WithKeys("left"),
+			key.WithHelp("←", "previous choice"),
+		),
+		forward: key.NewBinding(
+			key.WithKeys("right"),
+			key.WithHelp("→", "next choice"),
+		),
+		toggle: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "toggle"),
+		),
+		save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
+
+func (k sendFormKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.next, k.prev, k.previous, k.forward, k.save, k.cancel}
+}
+
+func (k sendFormKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.next, k.prev},
+		{k.previous, k.forward, k.toggle},
+		{k.save, k.cancel},
+	}
+}
+
+// sendFormView creates or edits a send. Text rows are inputs; type, dates
+// and options are picked with the arrow keys and space.
+type sendFormView struct {
+	// base is the send being edited, nil when creating one
+	base      *bw.Send
+	inputs    map[sendRow]*textinput.Model
+	focus     int // index into rows()
+	fileSend  bool
+	hidden    bool
+	hideEmail bool
+	// deletion and expiration index sendDeletions and sendExpirations, or
+	// are keepDate
+	deletion   int
+	expiration int
+	spinner    spinner.Model
+	help       help.Model
+	keys       *sendFormKeyMap
+	isSaving   bool
+	error      error
+	returnTo   view
+}
+
+func newSendFormView(base *bw.Send) sendFormView {
+	s := spinner.New()
+	s.Spinner = spinner.MiniDot
+	s.Style = l.NewStyle().Foreground(l.Color("8"))
+	f := sendFormView{
+		base:     base,
+		inputs:   map[sendRow]*textinput.Model{},
+		deletion: defaultSendDeletion,
+		spinner:  s,
+		help:     help.New(),
+		keys:     newSendFormKeyMap(),
+	}
+	for _, row := range []sendRow{sendNameRow, sendTextRow, sendFileRow, sendMaxAccessRow, sendPasswordRow, sendNotesRow} {
+		input := textinput.New()
+		input.Prompt = ""
+		f.inputs[row] = &input
+	}
+	f.inputs[sendFileRow].Placeholder = "path of the file to send"
+	f.inputs[sendMaxAccessRow].Placeholder = "no limit"
+	f.inputs[sendPasswordRow].EchoMode = textinput.EchoPassword
+	f.inputs[sendPasswordRow].EchoCharacter = '•'
+	if base != nil {
+		f.fileSend = base.Type == bw.SendTypeFile
+		f.hidden = base.Text.Hidden
+		f.hideEmail = base.HideEmail
+		f.deletion, f.expiration = keepDate, keepDate
+		f.inputs[sendNameRow].SetValue(base.Name)
+		f.inputs[sendTextRow].SetValue(base.Text.Text)
+		f.inputs[sendNotesRow].SetValue(base.Notes)
+		if base.MaxAccessCount != nil {
+			f.inputs[sendMaxAccessRow].SetValue(strconv.Itoa(*base.MaxAccessCount))
+		}
+		if base.PasswordSet {
+			f.inputs[sendPasswordRow].Placeholder = "unchanged"
+		}
+	}
+	f.setFocus(0)
+	return f
+}
+
+// newPasswordSend is a form for sending a secret, like an item's password.
+func newPasswordSend(name, secret string) sendFormView {
+	f := newSendFormView(nil)
+	f.inputs[sendNameRow].SetValue(name)
+	f.inputs[sendTextRow].SetValue(secret)
+	f.hidden = true
+	return f
+}
+
+// rows are the rows shown for the kind of send. The type and file of a send
+// can't change once it exists.
+func (f *sendFormView) rows() []sendRow {
+	rows := []sendRow{sendNameRow}
+	if f.base == nil {
+		rows = append(rows, sendTypeRow)
+	}
+	switch {
+	case !f.fileSend:
+		rows = append(rows, sendTextRow, sendHiddenRow)
+	case f.base == nil:
+		rows = append(rows, sendFileRow)
+	}
+	return append(rows, sendDeletionRow, sendExpirationRow, sendMaxAccessRow, sendPasswordRow, sendNotesRow, sendHideEmailRow)
+}
+
+func (f *sendFormView) focused() sendRow {
+	return f.rows()[f.focus]
+}
+
+func (f *sendFormView) setFocus(i int) tea.Cmd {
+	count := len(f.rows())
+	f.focus = (i + count) % count
+	var cmd tea.Cmd
+	for row, input := range f.inputs {
+		if row == f.focused() {
+			cmd = input.Focus()
+		} else {
+			input.Blur()
+		}
+	}
+	return cmd
+}
+
+// cycle moves through the choices of the focused row.
+func (f *sendFormView) cycle(delta int) {
+	switch f.focused() {
+	case sendTypeRow:
+		f.fileSend = !f.fileSend
+	case sendHiddenRow:
+		f.hidden = !f.hidden
+	case sendHideEmailRow:
+		f.hideEmail = !f.hideEmail
+	case sendDeletionRow:
+		f.deletion = f.cycleChoice(f.deletion, len(sendDeletions), delta)
+	case sendExpirationRow:
+		f.expiration = f.cycleChoice(f.expiration, len(sendExpirations), delta)
+	}
+}
+
+// cycleChoice steps through count choices, and keepDate as well when
+// editing.
+func (f *sendFormView) cycleChoice(i, count, delta int) int {
+	first := 0
+	if f.base != nil {
+		first = keepDate
+	}
+	count -= first
+	return (i-first+delta+count)%count + first
+}
+
+func (f *sendFormView) choice(row sendRow) string {
+	toggle := func(v bool) string {
+		if v {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+	date := func(i int, choices []sendDuration, current *time.Time) string {
+		if i != keepDate {
+			return "‹ " + choices[i].name + " ›"
+		}
+		if current == nil {
+			return "‹ unchanged, never ›"
+		}
+		return "‹ unchanged, " + current.Local().Format("2006-01-02 15:04") + " ›"
+	}
+	switch row {
+	case sendTypeRow:
+		if f.fileSend {
+			return "‹ file ›"
+		}
+		return "‹ text ›"
+	case sendHiddenRow:
+		return toggle(f.hidden)
+	case sendHideEmailRow:
+		return toggle(f.hideEmail)
+	case sendDeletionRow:
+		var current *time.Time
+		if f.base != nil {
+			current = f.base.DeletionDate
+		}
+		return date(f.deletion, sendDeletions, current)
+	case sendExpirationRow:
+		var current *time.Time
+		if f.base != nil {
+			current = f.base.ExpirationDate
+		}
+		return date(f.expiration, sendExpirations, current)
+	}
+	return ""
+}
+
+// send builds the send described by the form.
+func (f *sendFormView) send() (bw.Send, error) {
+	send := bw.Send{Type: bw.SendTypeText}
+	if f.base != nil {
+		send = *f.base
+	} else if f.fileSend {
+		send.Type = bw.SendTypeFile
+	}
+	send.Name = strings.TrimSpace(f.inputs[sendNameRow].Value())
+	send.Notes = f.inputs[sendNotesRow].Value()
+	send.HideEmail = f.hideEmail
+	send.Password = f.inputs[sendPasswordRow].Value()
+	if send.Type == bw.SendTypeText {
+		send.Text.Text = f.inputs[sendTextRow].Value()
+		send.Text.Hidden = f.hidden
+	}
+	now := time.Now()
+	if f.deletion != keepDate {
+		deletion := now.Add(sendDeletions[f.deletion].duration)
+		send.DeletionDate = &deletion
+	}
+	if f.expiration != keepDate {
+		send.ExpirationDate = nil
+		if d := sendExpirations[f.expiration].duration; d > 0 {
+			expiration := now.Add(d)
+			send.ExpirationDate = &expiration
+		}
+	}
+	send.MaxAccessCount = nil
+	if v := strings.TrimSpace(f.inputs[sendMaxAccessRow].Value()); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count < 1 {
+			return send, errors.New("Max views must be a positive number!")
+		}
+		send.MaxAccessCount = &count
+	}
+
+	switch {
+	case send.Name == "":
+		return send, errors.New("Name is required!")
+	case send.Type == bw.SendTypeText && send.Text.Text == "":
+		return send, errors.New("Text is required!")
+	case send.Type == bw.SendTypeFile && f.base == nil:
+		path := expandHome(strings.TrimSpace(f.inputs[sendFileRow].Value()))
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return send, errors.New("No such file!")
+		}
+		send.File.FileName = path
+	}
+	return send, nil
+}
+
+func (f sendFormView) Update(msg tea.Msg) (sendFormView, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		f.error = nil
+		_, isInput := f.inputs[f.focused()]
+		switch {
+		case key.Matches(msg, f.keys.next):
+			return f, f.setFocus(f.focus + 1)
+		case key.Matches(msg, f.keys.prev):
+			return f, f.setFocus(f.focus - 1)
+		case !isInput && key.Matches(msg, f.keys.previous):
+			f.cycle(-1)
+			return f, nil
+		case !isInput && (key.Matches(msg, f.keys.forward) || key.Matches(msg, f.keys.toggle)):
+			f.cycle(1)
+			return f, nil
+		}
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		f.spinner, cmd = f.spinner.Update(msg)
+		return f, cmd
+	}
+	input, ok := f.inputs[f.focused()]
+	if !ok {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	*input, cmd = input.Update(msg)
+	return f, cmd
+}
+
+func (f sendFormView) View() string {
+	maxLabelChars := 0
+	for _, label := range sendLabels {
+		if len(label) > maxLabelChars {
+			maxLabelChars = len(label)
+		}
+	}
+
+	var b strings.Builder
+	if f.isSaving {
+		b.WriteString(f.spinner.View() + " ")
+	} else {
+		b.WriteString("  ")
+	}
+	title := "NEW SEND"
+	if f.base != nil {
+		title = "EDIT SEND"
+	}
+	b.WriteString(titleStyle.Copy().MarginLeft(0).Render(title))
+	b.WriteString("\n")
+	for i, row := range f.rows() {
+		label := sendLabels[row]
+		padded := label + strings.Repeat(" ", maxLabelChars-len(label))
+		focused := i == f.focus
+		if focused {
+			b.WriteString("\n" + selectedPropertyStyle.Render("🢒 ") + itemLabelStyle.Render(padded))
+		} else {
+			b.WriteString("\n  " + itemLabelStyle.Render(padded))
+		}
+		if input, ok := f.inputs[row]; ok {
+			b.WriteString(input.View())
+		} else if focused {
+			b.WriteString(selectedPropertyStyle.Render(f.choice(row)))
+		} else {
+			b.WriteString(f.choice(row))
+		}
+	}
+	if f.base != nil && f.base.Type == bw.SendTypeFile {
+		b.WriteString("\n\n  " + itemLabelStyle.Render("Sending "+f.base.File.FileName+", "+f.base.File.SizeName))
+	}
+	if f.error != nil {
+		b.WriteString("\n\n  " + l.NewStyle().Foreground(l.Color("9")).Render(errorText(f.error)))
+	}
+	b.WriteString("\n\n  " + f.help.View(f.keys))
+	return b.String()
+}
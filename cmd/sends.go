@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	bw "bitwarden-tui/internal"
+)
+
+type sendsKeyMap struct {
+	create  key.Binding
+	edit    key.Binding
+	copyUrl key.Binding
+	delete  key.Binding
+	back    key.Binding
+}
+
+func newSendsKeyMap() *sendsKeyMap {
+	return &sendsKeyMap{
+		create: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "new send"),
+		),
+		edit: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "edit send"),
+		),
+		copyUrl: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "copy link"),
+		),
+		delete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "delete send"),
+		),
+		back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "go back"),
+		),
+	}
+}
+
+type sendsView struct {
+	list  list.Model
+	keys  *sendsKeyMap
+	sends []bw.Send
+}
+
+func newSendsView() sendsView {
+	keys := newSendsKeyMap()
+	sendList := newList("SENDS")
+	sendList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.create, keys.copyUrl, keys.back}
+	}
+	sendList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.create, keys.edit, keys.copyUrl, keys.delete, keys.back}
+	}
+	sendList.KeyMap.Quit = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
+	return sendsView{
+		list: sendList,
+		keys: keys,
+	}
+}
+
+// selected is the send under the cursor.
+func (v *sendsView) selected() (bw.Send, bool) {
+	i, ok := v.list.SelectedItem().(listItem)
+	if !ok {
+		return bw.Send{}, false
+	}
+	for _, send := range v.sends {
+		if send.Id == i.Id() {
+			return send, true
+		}
+	}
+	return bw.Send{}, false
+}
+
+type sendsMsg []bw.Send
+type sendSavedMsg bw.Send
+type sendDeletedMsg bw.Send
+
+// == CMD ==
+
+func (m *model) getSends() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		sends, err := m.vault.GetSends(ctx)
+		if err != nil {
+			return failed("Failed to fetch sends", err)
+		}
+		return sendsMsg(sends)
+	}
+}
+
+func (m *model) openSends() tea.Cmd {
+	m.view = SENDLIST
+	spinnerCmd := m.sendsView.list.StartSpinner()
+	return tea.Batch(spinnerCmd, m.getSends())
+}
+
+// openSendForm shows form, going back to the current view when done.
+func (m *model) openSendForm(form sendFormView) tea.Cmd {
+	form.returnTo = m.view
+	form.help.Width = m.sendsView.list.Help.Width
+	m.sendFormView = form
+	m.view = SENDFORM
+	return textinput.Blink
+}
+
+func (m *model) saveSend(send bw.Send) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		save := m.vault.CreateSend
+		if send.Id != "" {
+			save = m.vault.EditSend
+		}
+		saved, err := save(ctx, send)
+		if err != nil || saved == nil {
+			return failed("Failed to save send", err)
+		}
+		return sendSavedMsg(*saved)
+	}
+}
+
+func (m *model) deleteSend(send bw.Send) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		err := m.vault.DeleteSend(ctx, send.Id)
+		if err != nil {
+			return failed("Failed to delete send", err)
+		}
+		return sendDeletedMsg(send)
+	}
+}
+
+// copySendUrl copies the link to a send, which gives access to it.
+func (m *model) copySendUrl(send bw.Send) (string, tea.Cmd) {
+	if m.clipboard.Unsupported() {
+		return "clipboard unsupported!", nil
+	}
+	if err := m.clipboard.Copy(send.AccessUrl); err != nil {
+		return "failed to copy!", nil
+	}
+	return "copied link to " + send.Name, m.watchClipboard()
+}
+
+// == UTILS ==
+
+func sendItems(sends []bw.Send) []list.Item {
+	var items []list.Item
+	for _, send := range sends {
+		items = append(items, listItem{
+			id:          send.Id,
+			title:       send.Name,
+			description: describeSend(send),
+		})
+	}
+	return items
+}
+
+// describeSend sums up what a send holds and how long it lasts.
+func describeSend(send bw.Send) string {
+	parts := []string{"text"}
+	if send.Type == bw.SendTypeFile {
+		parts = []string{"file " + send.File.FileName}
+	}
+	views := strconv.Itoa(send.AccessCount) + " views"
+	if send.MaxAccessCount != nil {
+		views = strconv.Itoa(send.AccessCount) + "/" + strconv.Itoa(*send.MaxAccessCount) + " views"
+	}
+	parts = append(parts, views)
+	if send.PasswordSet {
+		parts = append(parts, "password")
+	}
+	if send.Disabled {
+		parts = append(parts, "disabled")
+	}
+	if send.ExpirationDate != nil {
+		parts = append(parts, "expires "+send.ExpirationDate.Local().Format("2006-01-02 15:04"))
+	} else if send.DeletionDate != nil {
+		parts = append(parts, "deleted "+send.DeletionDate.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
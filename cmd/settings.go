@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	l "github.com/charmbracelet/lipgloss"
+
+	bw "bitwarden-tui/internal"
+)
+
+type settingsRow int
+
+const (
+	serverRow settingsRow = iota
+	apiRow
+	identityRow
+	webVaultRow
+)
+
+var settingsLabels = []string{
+	serverRow:   "Server",
+	apiRow:      "API",
+	identityRow: "Identity",
+	webVaultRow: "Web vault",
+}
+
+type settingsKeyMap struct {
+	next   key.Binding
+	prev   key.Binding
+	save   key.Binding
+	cancel key.Binding
+}
+
+func newSettingsKeyMap() *settingsKeyMap {
+	return &settingsKeyMap{
+		next: key.NewBinding(
+			key.WithKeys("tab", "down"),
+			key.WithHelp("tab/↓", "next"),
+		),
+		prev: key.NewBinding(
+			key.WithKeys("shift+tab", "up"),
+			key.WithHelp("shift+tab/↑", "previous"),
+		),
+		save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
+
+func (k settingsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.next, k.prev, k.save, k.cancel}
+}
+
+func (k settingsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.next, k.prev}, {k.save, k.cancel}}
+}
+
+// settingsView sets the server the vault is on. Saving a new one logs out.
+type settingsView struct {
+	inputs []textinput.Model
+	focus  int
+	// current is the server as it was when the view opened
+	current   bw.ServerConfig
+	spinner   spinner.Model
+	help      help.Model
+	keys      *settingsKeyMap
+	isLoading bool
+	error     error
+	returnTo  view
+}
+
+type serverMsg bw.ServerConfig
+type serverChangedMsg bw.ServerConfig
+
+func newSettingsView() settingsView {
+	s := spinner.New()
+	s.Spinner = spinner.MiniDot
+	s.Style = l.NewStyle().Foreground(l.Color("8"))
+	v := settingsView{
+		spinner: s,
+		help:    help.New(),
+		keys:    newSettingsKeyMap(),
+	}
+	for row := range settingsLabels {
+		input := textinput.New()
+		input.Prompt = ""
+		switch settingsRow(row) {
+		case serverRow:
+			input.Placeholder = bw.DefaultServer
+		default:
+			input.Placeholder = "derived from the server"
+		}
+		v.inputs = append(v.inputs, input)
+	}
+	v.setFocus(0)
+	return v
+}
+
+func (v *settingsView) setFocus(i int) tea.Cmd {
+	v.focus = (i + len(v.inputs)) % len(v.inputs)
+	var cmd tea.Cmd
+	for i := range v.inputs {
+		if i == v.focus {
+			cmd = v.inputs[i].Focus()
+		} else {
+			v.inputs[i].Blur()
+		}
+	}
+	return cmd
+}
+
+func (v *settingsView) setServer(server bw.ServerConfig) {
+	v.current = server
+	v.inputs[serverRow].SetValue(server.Url)
+	v.inputs[apiRow].SetValue(server.Api)
+	v.inputs[identityRow].SetValue(server.Identity)
+	v.inputs[webVaultRow].SetValue(server.WebVault)
+	for i := range v.inputs {
+		v.inputs[i].CursorEnd()
+	}
+}
+
+// server is the server the inputs describe.
+func (v *settingsView) server() (bw.ServerConfig, error) {
+	values := make([]string, len(v.inputs))
+	for i, input := range v.inputs {
+		value := strings.TrimSpace(input.Value())
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return bw.ServerConfig{}, errors.New(settingsLabels[i] + " must be an http(s) URL!")
+		}
+		values[i] = strings.TrimRight(value, "/")
+	}
+	return bw.ServerConfig{
+		Url:      values[serverRow],
+		Api:      values[apiRow],
+		Identity: values[identityRow],
+		WebVault: values[webVaultRow],
+	}, nil
+}
+
+// == CMD ==
+
+// openSettings shows the server settings, going back to the current view
+// when done.
+func (m *model) openSettings() tea.Cmd {
+	v := newSettingsView()
+	v.returnTo = m.view
+	v.help.Width = m.listView.list.Help.Width
+	v.isLoading = true
+	m.settingsView = v
+	m.view = SETTINGS
+	return tea.Batch(textinput.Blink, v.spinner.Tick, m.getServer())
+}
+
+func (m *model) getServer() tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		server, err := m.vault.GetServer(ctx)
+		if err != nil || server == nil {
+			return failed("Couldn't read the server", err)
+		}
+		return serverMsg(*server)
+	}
+}
+
+func (m *model) setServer(server bw.ServerConfig) tea.Cmd {
+	ctx, done := m.calls.start(m.view)
+	return func() tea.Msg {
+		defer done()
+		if err := m.vault.SetServer(ctx, server); err != nil {
+			return failed("Failed to change the server", err)
+		}
+		return serverChangedMsg(server)
+	}
+}
+
+// confirmServer switches to server, asking first when that logs out. onNo
+// runs when the answer is no.
+func (m *model) confirmServer(server bw.ServerConfig, onNo tea.Cmd) tea.Cmd {
+	status := m.inputView.status
+	if status != nil && status.Status == bw.StatusUnauthenticated {
+		return m.setServer(server)
+	}
+	name := server.Url
+	if name == "" {
+		name = bw.DefaultServer
+	}
+	prompt := "Switch to " + name + "? This logs you out."
+	if status != nil && status.UserEmail != "" {
+		prompt = "Switch to " + name + "? This logs " + status.UserEmail + " out."
+	}
+	m.confirm = &confirmation{
+		prompt: prompt,
+		onYes: func() tea.Cmd {
+			return m.setServer(server)
+		},
+		onNo: onNo,
+	}
+	return nil
+}
+
+// == UPDATE ==
+
+func (m model) updateSettings(msg tea.Msg) (tea.Model, tea.Cmd) {
+	v := &m.settingsView
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if v.isLoading {
+			break
+		}
+		v.error = nil
+		switch {
+		case msg.String() == "ctrl+c":
+			return m, tea.Quit
+		case key.Matches(msg, v.keys.cancel):
+			m.view = v.returnTo
+			return m, nil
+		case key.Matches(msg, v.keys.next):
+			return m, v.setFocus(v.focus + 1)
+		case key.Matches(msg, v.keys.prev):
+			return m, v.setFocus(v.focus - 1)
+		case key.Matches(msg, v.keys.save):
+			server, err := v.server()
+			if err != nil {
+				v.error = err
+				return m, nil
+			}
+			if server == v.current {
+				m.view = v.returnTo
+				return m, nil
+			}
+			return m, m.confirmServer(server, nil)
+		}
+	case serverMsg:
+		v.isLoading = false
+		v.setServer(bw.ServerConfig(msg))
+		return m, nil
+	case errorMsg:
+		v.isLoading = false
+		v.error = msg.err
+		return m, nil
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		v.spinner, cmd = v.spinner.Update(msg)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	v.inputs[v.focus], cmd = v.inputs[v.focus].Update(msg)
+	return m, cmd
+}
+
+// == VIEW ==
+
+func renderSettings(m model) string {
+	v := m.settingsView
+	maxLabelChars := 0
+	for _, label := range settingsLabels {
+		if len(label) > maxLabelChars {
+			maxLabelChars = len(label)
+		}
+	}
+
+	var b strings.Builder
+	if v.isLoading {
+		b.WriteString(v.spinner.View() + " ")
+	} else {
+		b.WriteString("  ")
+	}
+	b.WriteString(titleStyle.Copy().MarginLeft(0).Render("SERVER"))
+	b.WriteString("\n")
+	for i, input := range v.inputs {
+		padded := settingsLabels[i] + strings.Repeat(" ", maxLabelChars-len(settingsLabels[i]))
+		if i == v.focus {
+			b.WriteString("\n" + selectedPropertyStyle.Render("🢒 ") + itemLabelStyle.Render(padded))
+		} else {
+			b.WriteString("\n  " + itemLabelStyle.Render(padded))
+		}
+		b.WriteString(input.View())
+	}
+	b.WriteString("\n\n  " + itemLabelStyle.Render("Changing the server logs out."))
+	if v.error != nil {
+		b.WriteString("\n\n  " + l.NewStyle().Foreground(l.Color("9")).Render(errorText(v.error)))
+	}
+	b.WriteString("\n\n  " + v.help.View(v.keys))
+	return appStyle.Render(b.String())
+}
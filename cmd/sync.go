@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type syncedMsg struct {
+	// err is why the sync failed, nil when it went through
+	err error
+}
+type lastSyncMsg struct{ time *time.Time }
+type syncTickMsg struct {
+	// id tells apart the timers of successive unlocks and syncs
+	id int
+}
+
+// == CMD ==
+
+// sync runs bw sync in the background. The items are reloaded once it's
+// done. A sync the user waits on can be cancelled with esc, a periodic one
+// only by locking.
+func (m *model) sync(periodic bool) tea.Cmd {
+	m.syncing = true
+	var ctx context.Context
+	var done context.CancelFunc
+	if periodic {
+		ctx, done = m.calls.background(m.calls.syncTimeout)
+	} else {
+		ctx, done = m.calls.startSync(m.view)
+	}
+	return func() tea.Msg {
+		defer done()
+		return syncedMsg{err: m.vault.Sync(ctx)}
+	}
+}
+
+func (m *model) getLastSync() tea.Cmd {
+	ctx, done := m.calls.detached()
+	return func() tea.Msg {
+		defer done()
+		last, err := m.vault.LastSync(ctx)
+		if err != nil {
+			// the header goes without it
+			return nil
+		}
+		return lastSyncMsg{last}
+	}
+}
+
+// scheduleSync starts waiting for the next periodic sync, replacing the
+// timer that was running.
+func (m *model) scheduleSync() tea.Cmd {
+	m.syncTimers++
+	if m.syncEvery <= 0 {
+		return nil
+	}
+	id := m.syncTimers
+	return tea.Tick(m.syncEvery, func(time.Time) tea.Msg {
+		return syncTickMsg{id: id}
+	})
+}
+
+// == UPDATE ==
+
+// updateSync handles the messages of syncing, whichever view is showing.
+func (m *model) updateSync(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case syncTickMsg:
+		if msg.id != m.syncTimers || m.view == PASSINPUT {
+			return nil
+		}
+		if m.syncing {
+			return m.scheduleSync()
+		}
+		return m.sync(true)
+	case syncedMsg:
+		m.syncing = false
+		if m.view == PASSINPUT {
+			return nil
+		}
+		m.listView.list.StopSpinner()
+		scheduleCmd := m.scheduleSync()
+		if msg.err != nil {
+			err := failed("Sync failed", msg.err).err
+			m.lastError = err
+			return tea.Batch(scheduleCmd, m.notify(errorText(err)))
+		}
+		return tea.Batch(scheduleCmd, m.notify("synced"), m.getLastSync(), m.getItems())
+	case lastSyncMsg:
+		m.lastSync = msg.time
+		m.setListTitle()
+	}
+	return nil
+}
+
+// notify shows a status message in the view that is showing, or in the
+// item list when that view has no place for one.
+func (m *model) notify(status string) tea.Cmd {
+	switch m.view {
+	case PASSITEM:
+		return m.itemView.item.NewStatusMessage(status)
+	case TRASHLIST:
+		return m.trashView.list.NewStatusMessage(status)
+	case FOLDERLIST:
+		return m.folderView.list.NewStatusMessage(status)
+	case SENDLIST:
+		return m.sendsView.list.NewStatusMessage(status)
+	}
+	return m.listView.list.NewStatusMessage(status)
+}
+
+// == VIEW ==
+
+// setListTitle names the item list after the folder it shows, along with
+// when the vault last synced.
+func (m *model) setListTitle() {
+	title := "BITWARDEN"
+	if name := folderName(m.folderView.folders, m.listView.filter.FolderId); name != "" {
+		title += " · " + name
+	}
+	if m.lastSync != nil {
+		title += " · synced " + formatSyncTime(*m.lastSync)
+	}
+	m.listView.list.Title = title
+}
+
+// formatSyncTime leaves out the date of a sync that happened today.
+func formatSyncTime(t time.Time) string {
+	t = t.Local()
+	if t.Format("2006-01-02") == time.Now().Format("2006-01-02") {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
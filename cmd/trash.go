@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+type trashKeyMap struct {
+	restore key.Binding
+	purge   key.Binding
+	back    key.Binding
+}
+
+func newTrashKeyMap() *trashKeyMap {
+	return &trashKeyMap{
+		restore: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "restore item"),
+		),
+		purge: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "delete permanently"),
+		),
+		back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "go back"),
+		),
+	}
+}
+
+type trashView struct {
+	list list.Model
+	keys *trashKeyMap
+}
+
+func newTrashView() trashView {
+	keys := newTrashKeyMap()
+	trashList := newList("TRASH")
+	trashList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.restore, keys.back}
+	}
+	trashList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.restore, keys.purge, keys.back}
+	}
+	// esc goes back to the vault instead of quitting
+	trashList.KeyMap.Quit = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
+	return trashView{
+		list: trashList,
+		keys: keys,
+	}
+}
@@ -0,0 +1,551 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is an operation a backend can't do.
+var ErrUnsupported = errors.New("not supported by this backend")
+
+// DefaultServer is Bitwarden's own cloud.
+const DefaultServer = "https://vault.bitwarden.com"
+
+// cloudUrls are the API and identity servers of Bitwarden's cloud regions,
+// which don't live under the web vault.
+var cloudUrls = map[string][2]string{
+	"https://vault.bitwarden.com": {"https://api.bitwarden.com", "https://identity.bitwarden.com"},
+	"https://vault.bitwarden.eu":  {"https://api.bitwarden.eu", "https://identity.bitwarden.eu"},
+}
+
+// API is a Vault that talks to the Bitwarden server itself and decrypts
+// the vault in-process, rather than going through the bw CLI. It keeps the
+// decrypted vault in memory from unlocking to locking.
+type API struct {
+	// Server is the web vault, DefaultServer when empty. ApiUrl and
+	// IdentityUrl default to its /api and /identity. Left empty, they
+	// are the ones last set with SetServer.
+	Server      string
+	ApiUrl      string
+	IdentityUrl string
+	WebVaultUrl string
+	// StatePath is the file the login is kept in between runs. Nothing is
+	// kept when it is empty.
+	StatePath string
+	Client    *http.Client
+
+	mu     sync.Mutex
+	loaded bool
+	state  apiState
+	// key decrypts the vault, nil while locked
+	key   *symmetricKey
+	vault apiVault
+}
+
+// apiState is the login, as kept in StatePath. The vault's key in it can
+// only be decrypted with the master password.
+type apiState struct {
+	Email  string    `json:"email"`
+	UserId string    `json:"userId"`
+	Kdf    KdfConfig `json:"kdf"`
+	// Key is the vault's key, encrypted with the master key
+	Key          string     `json:"key"`
+	AccessToken  string     `json:"accessToken"`
+	RefreshToken string     `json:"refreshToken"`
+	Expiry       time.Time  `json:"expiry"`
+	DeviceId     string     `json:"deviceId"`
+	LastSync     *time.Time `json:"lastSync"`
+	// Server is what SetServer set
+	Server ServerConfig `json:"server"`
+}
+
+var _ Vault = (*API)(nil)
+
+// NewAPI talks to server, keeping the login in the user's config directory.
+func NewAPI(server string) *API {
+	a := &API{Server: server}
+	if dir, err := os.UserConfigDir(); err == nil {
+		a.StatePath = filepath.Join(dir, "bwtui", "api.json")
+	}
+	return a
+}
+
+func (a *API) server() string {
+	if a.Server == "" {
+		return DefaultServer
+	}
+	return strings.TrimRight(a.Server, "/")
+}
+
+func (a *API) apiUrl() string {
+	if a.ApiUrl != "" {
+		return strings.TrimRight(a.ApiUrl, "/")
+	}
+	if urls, ok := cloudUrls[a.server()]; ok {
+		return urls[0]
+	}
+	return a.server() + "/api"
+}
+
+func (a *API) identityUrl() string {
+	if a.IdentityUrl != "" {
+		return strings.TrimRight(a.IdentityUrl, "/")
+	}
+	if urls, ok := cloudUrls[a.server()]; ok {
+		return urls[1]
+	}
+	return a.server() + "/identity"
+}
+
+func (a *API) webVaultUrl() string {
+	if a.WebVaultUrl != "" {
+		return strings.TrimRight(a.WebVaultUrl, "/")
+	}
+	return a.server()
+}
+
+func (a *API) client() *http.Client {
+	if a.Client == nil {
+		return http.DefaultClient
+	}
+	return a.Client
+}
+
+// load reads the login kept from an earlier run, once.
+func (a *API) load() {
+	if a.loaded {
+		return
+	}
+	a.loaded = true
+	if a.StatePath == "" {
+		return
+	}
+	data, err := os.ReadFile(a.StatePath)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &a.state)
+	if a.Server == "" && a.ApiUrl == "" && a.IdentityUrl == "" && a.WebVaultUrl == "" {
+		a.useServer(a.state.Server)
+	}
+}
+
+func (a *API) useServer(server ServerConfig) {
+	a.Server, a.ApiUrl, a.IdentityUrl, a.WebVaultUrl = server.Url, server.Api, server.Identity, server.WebVault
+}
+
+func (a *API) save() error {
+	if a.StatePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(a.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.StatePath), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(a.StatePath, data, 0600)
+}
+
+func (a *API) Status(ctx context.Context) (*Status, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	status := &Status{
+		ServerUrl: a.server(),
+		LastSync:  a.state.LastSync,
+		UserEmail: a.state.Email,
+		UserId:    a.state.UserId,
+		Status:    StatusUnauthenticated,
+	}
+	switch {
+	case a.key != nil:
+		status.Status = StatusUnlocked
+	case a.state.Key != "" && (a.state.RefreshToken != "" || a.state.AccessToken != ""):
+		status.Status = StatusLocked
+	}
+	return status, nil
+}
+
+func (a *API) Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	email = strings.TrimSpace(email)
+	var kdf KdfConfig
+	err := a.send(ctx, http.MethodPost, a.identityUrl()+"/accounts/prelogin", map[string]string{"email": email}, &kdf)
+	if err != nil {
+		return err
+	}
+	key, err := masterKey(password, email, kdf)
+	if err != nil {
+		return err
+	}
+	hash := masterPasswordHash(key, password)
+	if twoFactor != nil && twoFactor.Method == TwoFactorEmail && twoFactor.Code == "" {
+		err := a.send(ctx, http.MethodPost, a.apiUrl()+"/two-factor/send-email-login", map[string]string{
+			"email":              email,
+			"masterPasswordHash": hash,
+			"deviceIdentifier":   a.deviceId(),
+		}, nil)
+		if err != nil {
+			return err
+		}
+		return ErrTwoFactorRequired
+	}
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {email},
+		"password":   {hash},
+		"scope":      {"api offline_access"},
+	}
+	if twoFactor != nil {
+		form.Set("twoFactorProvider", strconv.Itoa(int(twoFactor.Method)))
+		form.Set("twoFactorToken", twoFactor.Code)
+		form.Set("twoFactorRemember", "0")
+	}
+	token, err := a.token(ctx, form, email)
+	if err != nil {
+		return err
+	}
+	a.state = apiState{Email: email, Kdf: kdf, DeviceId: a.state.DeviceId}
+	a.setToken(token)
+	if err := a.save(); err != nil {
+		return err
+	}
+	return a.unlock(ctx, key)
+}
+
+func (a *API) LoginAPIKey(ctx context.Context, clientId, clientSecret string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	token, err := a.token(ctx, url.Values{
+		"grant_type":    {"client_credentials"},
+		"scope":         {"api"},
+		"client_id":     {clientId},
+		"client_secret": {clientSecret},
+	}, "")
+	if err != nil {
+		return err
+	}
+	a.state = apiState{DeviceId: a.state.DeviceId, Kdf: token.KdfConfig}
+	a.setToken(token)
+	return a.save()
+}
+
+func (a *API) Unlock(ctx context.Context, password string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	if a.state.Key == "" {
+		return ErrNotLoggedIn
+	}
+	if a.state.Kdf.Iterations == 0 {
+		// not every API key login tells the KDF
+		err := a.send(ctx, http.MethodPost, a.identityUrl()+"/accounts/prelogin", map[string]string{"email": a.state.Email}, &a.state.Kdf)
+		if err != nil {
+			return err
+		}
+	}
+	key, err := masterKey(password, a.state.Email, a.state.Kdf)
+	if err != nil {
+		return err
+	}
+	return a.unlock(ctx, key)
+}
+
+// unlock decrypts the vault's key with the master key and fetches the
+// vault.
+func (a *API) unlock(ctx context.Context, masterKey []byte) error {
+	stretched, err := stretch(masterKey)
+	if err != nil {
+		return err
+	}
+	key, err := stretched.decryptKey(a.state.Key)
+	if err != nil {
+		return ErrInvalidPassword
+	}
+	a.key = &key
+	if err := a.sync(ctx); err != nil {
+		a.lock()
+		return err
+	}
+	return nil
+}
+
+func (a *API) GetServer(ctx context.Context) (*ServerConfig, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	return &ServerConfig{Url: a.Server, Api: a.ApiUrl, Identity: a.IdentityUrl, WebVault: a.WebVaultUrl}, nil
+}
+
+// SetServer forgets the login, which belongs to the old server.
+func (a *API) SetServer(ctx context.Context, server ServerConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	a.lock()
+	a.state = apiState{DeviceId: a.state.DeviceId, Server: server}
+	a.useServer(server)
+	return a.save()
+}
+
+func (a *API) Lock(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lock()
+	return nil
+}
+
+func (a *API) lock() {
+	a.key = nil
+	a.vault = apiVault{}
+}
+
+// tokenResponse is what the identity server answers a login with. The
+// vault's key and KDF come along with the tokens.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Key          string `json:"key"`
+	KdfConfig
+}
+
+// token logs in with form. The email, if known, goes along in a header
+// the server wants for password logins.
+func (a *API) token(ctx context.Context, form url.Values, email string) (*tokenResponse, error) {
+	if form.Get("client_id") == "" {
+		form.Set("client_id", "cli")
+	}
+	form.Set("deviceType", "25") // Linux CLI
+	form.Set("deviceIdentifier", a.deviceId())
+	form.Set("deviceName", "bwtui")
+	req, err := http.NewRequest(http.MethodPost, a.identityUrl()+"/connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if email != "" {
+		req.Header.Set("Auth-Email", base64.RawURLEncoding.EncodeToString([]byte(email)))
+	}
+	var token tokenResponse
+	if err := a.do(ctx, req, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (a *API) setToken(token *tokenResponse) {
+	a.state.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		a.state.RefreshToken = token.RefreshToken
+	}
+	a.state.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	if token.Key != "" {
+		a.state.Key = token.Key
+	}
+	claims := tokenClaims(token.AccessToken)
+	a.state.UserId = claims.Sub
+	if a.state.Email == "" {
+		a.state.Email = claims.Email
+	}
+}
+
+// tokenClaims reads who an access token is for. The token is only ever
+// checked by the server.
+func tokenClaims(token string) (claims struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+}) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(payload, &claims)
+	return
+}
+
+// refresh gets a new access token once the current one is about to expire.
+// Logins with an API key have no refresh token and must log in again.
+func (a *API) refresh(ctx context.Context) error {
+	if time.Until(a.state.Expiry) > time.Minute {
+		return nil
+	}
+	if a.state.RefreshToken == "" {
+		return ErrNotLoggedIn
+	}
+	token, err := a.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {a.state.RefreshToken},
+	}, "")
+	var serverErr *serverError
+	if errors.As(err, &serverErr) {
+		// the session ended on the server
+		a.state.AccessToken, a.state.RefreshToken = "", ""
+		_ = a.save()
+		return ErrNotLoggedIn
+	} else if err != nil {
+		return err
+	}
+	a.setToken(token)
+	return a.save()
+}
+
+// call sends body as JSON to an API endpoint as the logged in user and
+// decodes the answer into out, unless it is nil.
+func (a *API) call(ctx context.Context, method, path string, body, out interface{}) error {
+	if a.state.AccessToken == "" {
+		return ErrNotLoggedIn
+	}
+	if err := a.refresh(ctx); err != nil {
+		return err
+	}
+	req, err := newJSONRequest(method, a.apiUrl()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.state.AccessToken)
+	return a.do(ctx, req, out)
+}
+
+// send is call for the endpoints used before logging in.
+func (a *API) send(ctx context.Context, method, url string, body, out interface{}) error {
+	req, err := newJSONRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	return a.do(ctx, req, out)
+}
+
+func newJSONRequest(method, url string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	return req, nil
+}
+
+// serverError is a request the server turned down, with the reason it
+// gave.
+type serverError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *serverError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "server answered " + strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+}
+
+// do sends req, mapping failures onto the Err values where they fit.
+func (a *API) do(ctx context.Context, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Device-Type", "25")
+	resp, err := a.client().Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	if resp.StatusCode >= 400 {
+		return responseError(resp.StatusCode, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	return nil
+}
+
+func responseError(status int, body []byte) error {
+	var answer struct {
+		Message          string `json:"message"`
+		ErrorDescription string `json:"error_description"`
+		ErrorModel       struct {
+			Message string `json:"message"`
+		} `json:"errorModel"`
+		TwoFactorProviders []json.RawMessage `json:"twoFactorProviders"`
+	}
+	_ = json.Unmarshal(body, &answer)
+	msg := answer.ErrorModel.Message
+	if msg == "" {
+		msg = answer.Message
+	}
+	if msg == "" {
+		msg = answer.ErrorDescription
+	}
+	switch {
+	case len(answer.TwoFactorProviders) > 0:
+		return ErrTwoFactorRequired
+	case strings.Contains(msg, "Two-step token is invalid"):
+		return ErrInvalidTwoFactor
+	case strings.Contains(msg, "Username or password is incorrect"),
+		msg == "invalid_username_or_password":
+		return ErrInvalidPassword
+	case status == http.StatusUnauthorized:
+		return ErrNotLoggedIn
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	}
+	return &serverError{StatusCode: status, Message: msg}
+}
+
+// deviceId is the id this install logs in as, made up on first use.
+func (a *API) deviceId() string {
+	if a.state.DeviceId == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		h := hex.EncodeToString(b)
+		a.state.DeviceId = h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	}
+	return a.state.DeviceId
+}
@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// The payloads in testdata/api were recorded for this account, with the
+// vault encrypted as the Bitwarden clients do it.
+const (
+	stubEmail    = "User@Example.com"
+	stubPassword = "correct horse"
+	// stubHash is the master password hash the server expects at login
+	stubHash = "GawDT/kzVPcgqsGddJHjFOGS1q79ym7be4hCTiGCvrk="
+)
+
+// apiStub plays the Bitwarden server with the recorded payloads.
+type apiStub struct {
+	mu   sync.Mutex
+	sync []byte
+	// paths are the requests served, in order
+	paths []string
+}
+
+func newAPIStub(t *testing.T) (*API, *apiStub) {
+	t.Helper()
+	stub := &apiStub{sync: readTestdata(t, "sync.json")}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.paths = append(stub.paths, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/identity/accounts/prelogin":
+			w.Write(readTestdata(t, "prelogin.json"))
+		case "/identity/connect/token":
+			if r.FormValue("password") != stubHash {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"invalid_username_or_password"}`))
+				return
+			}
+			w.Write(readTestdata(t, "token.json"))
+		case "/api/sync":
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write(stub.sync)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	api := &API{
+		Server:    server.URL,
+		StatePath: filepath.Join(t.TempDir(), "api.json"),
+		Client:    server.Client(),
+	}
+	return api, stub
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "api", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAPILoginDecryptsVault(t *testing.T) {
+	api, stub := newAPIStub(t)
+	ctx := context.Background()
+	if err := api.Login(ctx, stubEmail, stubPassword, nil); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	want := []string{
+		"POST /identity/accounts/prelogin",
+		"POST /identity/connect/token",
+		"GET /api/sync",
+	}
+	if len(stub.paths) != len(want) {
+		t.Fatalf("requests = %v, want %v", stub.paths, want)
+	}
+	for i := range want {
+		if stub.paths[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, stub.paths[i], want[i])
+		}
+	}
+
+	items, err := api.GetItems(ctx, FilterOptions{})
+	if err != nil {
+		t.Fatalf("GetItems: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	login, err := api.GetItem(ctx, "c1")
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if login.Name != "GitHub" || login.Notes != "my notes" || login.Login.Username != "octo" ||
+		login.Login.Password != "hunter2" || login.Login.Uris[0].Uri != "https://github.com" {
+		t.Errorf("login item = %+v", login)
+	}
+	if len(login.Fields) != 1 || login.Fields[0].Name != "pin" || login.Fields[0].Value != "1234" {
+		t.Errorf("fields = %+v", login.Fields)
+	}
+	// c2 is encrypted with the organization's key
+	card, err := api.GetItem(ctx, "c2")
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if card.Name != "Org Visa" || card.Card.Number != "4111111111111111" {
+		t.Errorf("card item = %+v", card.Card)
+	}
+	// c3 has a key of its own, and is in the trash
+	trash, err := api.GetTrash(ctx)
+	if err != nil {
+		t.Fatalf("GetTrash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].Name != "Keyed note" || trash[0].Notes != "secret note" {
+		t.Errorf("trash = %+v", trash)
+	}
+
+	folders, err := api.GetFolders(ctx)
+	if err != nil || len(folders) != 1 || folders[0].Name != "Work" {
+		t.Errorf("GetFolders = %+v, %v", folders, err)
+	}
+	sends, err := api.GetSends(ctx)
+	if err != nil || len(sends) != 1 || sends[0].Name != "shared" || sends[0].Text.Text != "hello" {
+		t.Errorf("GetSends = %+v, %v", sends, err)
+	}
+}
+
+func TestAPILoginWrongPassword(t *testing.T) {
+	api, _ := newAPIStub(t)
+	err := api.Login(context.Background(), stubEmail, "wrong", nil)
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("Login = %v, want ErrInvalidPassword", err)
+	}
+}
+
+func TestAPIUnlockFromState(t *testing.T) {
+	api, _ := newAPIStub(t)
+	ctx := context.Background()
+	if err := api.Login(ctx, stubEmail, stubPassword, nil); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	// a new run reads the login from StatePath
+	next := &API{Server: api.Server, StatePath: api.StatePath, Client: api.Client}
+	status, err := next.Status(ctx)
+	if err != nil || status.Status != StatusLocked {
+		t.Fatalf("Status = %+v, %v, want locked", status, err)
+	}
+	if err := next.Unlock(ctx, "wrong"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Unlock with the wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if err := next.Unlock(ctx, stubPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if item, err := next.GetItem(ctx, "c1"); err != nil || item.Login.Password != "hunter2" {
+		t.Errorf("GetItem after unlock = %+v, %v", item, err)
+	}
+}
+
+func TestAPISyncFailureKeepsVault(t *testing.T) {
+	api, stub := newAPIStub(t)
+	ctx := context.Background()
+	if err := api.Login(ctx, stubEmail, stubPassword, nil); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// a new item, then a send that can't be read
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(stub.sync, &payload); err != nil {
+		t.Fatal(err)
+	}
+	var ciphers []map[string]interface{}
+	if err := json.Unmarshal(payload["ciphers"], &ciphers); err != nil {
+		t.Fatal(err)
+	}
+	extra := map[string]interface{}{}
+	for k, v := range ciphers[0] {
+		extra[k] = v
+	}
+	extra["id"] = "c4"
+	ciphers = append(ciphers, extra)
+	payload["ciphers"], _ = json.Marshal(ciphers)
+	payload["sends"] = json.RawMessage(`[{"id": 5}]`)
+	stub.mu.Lock()
+	stub.sync, _ = json.Marshal(payload)
+	stub.mu.Unlock()
+
+	if err := api.Sync(ctx); !errors.Is(err, ErrUnexpectedOutput) {
+		t.Fatalf("Sync = %v, want ErrUnexpectedOutput", err)
+	}
+	items, err := api.GetItems(ctx, FilterOptions{})
+	if err != nil {
+		t.Fatalf("GetItems: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("got %d items after a failed sync, want the 2 from before", len(items))
+	}
+	if sends, _ := api.GetSends(ctx); len(sends) != 1 || sends[0].Text.Text != "hello" {
+		t.Errorf("sends after a failed sync = %+v", sends)
+	}
+}
@@ -0,0 +1,679 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// apiVault is the decrypted vault of an unlocked API.
+type apiVault struct {
+	items   []Item
+	folders []Folder
+	sends   []Send
+	// ciphers has what the server knows of each item that Item doesn't
+	// model, by item id
+	ciphers map[string]cipherMeta
+	orgKeys map[string]symmetricKey
+}
+
+// cipherMeta is what it takes to encrypt an item again.
+type cipherMeta struct {
+	OrganizationId string `json:"organizationId"`
+	// Key is the item's own key, if it has one, encrypted with the user's
+	// or the organization's key
+	Key           string     `json:"key"`
+	Reprompt      int        `json:"reprompt"`
+	RevisionDate  *time.Time `json:"revisionDate"`
+	CollectionIds []string   `json:"collectionIds"`
+}
+
+type syncResponse struct {
+	Profile struct {
+		Id            string `json:"id"`
+		Email         string `json:"email"`
+		Key           string `json:"key"`
+		PrivateKey    string `json:"privateKey"`
+		Organizations []struct {
+			Id  string `json:"id"`
+			Key string `json:"key"`
+		} `json:"organizations"`
+	} `json:"profile"`
+	Folders []Folder          `json:"folders"`
+	Ciphers []json.RawMessage `json:"ciphers"`
+	Sends   []json.RawMessage `json:"sends"`
+}
+
+func (a *API) Sync(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	return a.sync(ctx)
+}
+
+func (a *API) LastSync(ctx context.Context) (*time.Time, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	return a.state.LastSync, nil
+}
+
+// sync fetches and decrypts the whole vault.
+func (a *API) sync(ctx context.Context) error {
+	var resp syncResponse
+	if err := a.call(ctx, http.MethodGet, "/sync?excludeDomains=true", nil, &resp); err != nil {
+		return err
+	}
+	vault := apiVault{
+		ciphers: map[string]cipherMeta{},
+		orgKeys: map[string]symmetricKey{},
+	}
+	if resp.Profile.PrivateKey != "" && len(resp.Profile.Organizations) > 0 {
+		der, err := a.key.decrypt(resp.Profile.PrivateKey)
+		if err != nil {
+			return err
+		}
+		privateKey, err := parsePrivateKey(der)
+		if err != nil {
+			return err
+		}
+		for _, org := range resp.Profile.Organizations {
+			// items of an organization whose key is unusable show as
+			// undecryptable
+			if key, err := decryptRSA(privateKey, org.Key); err == nil {
+				vault.orgKeys[org.Id] = key
+			}
+		}
+	}
+	for _, folder := range resp.Folders {
+		name, err := a.key.decryptString(folder.Name)
+		if err != nil {
+			return err
+		}
+		vault.folders = append(vault.folders, Folder{Id: folder.Id, Name: name})
+	}
+	for _, raw := range resp.Ciphers {
+		item, meta, err := a.decryptCipher(&vault, raw)
+		if err != nil {
+			return err
+		}
+		vault.items = append(vault.items, item)
+		vault.ciphers[item.Id] = meta
+	}
+	for _, raw := range resp.Sends {
+		send, err := a.decryptSend(raw)
+		if err != nil {
+			return err
+		}
+		vault.sends = append(vault.sends, send)
+	}
+	// only a vault that decrypted in full replaces the one read before
+	a.vault = vault
+	now := time.Now()
+	a.state.LastSync = &now
+	if resp.Profile.Key != "" {
+		a.state.Key = resp.Profile.Key
+	}
+	return a.save()
+}
+
+// itemStrings are the members of an item that the server keeps encrypted.
+func itemStrings(i *Item) []*string {
+	c, id := &i.Card, &i.Identity
+	s := []*string{
+		&i.Name, &i.Notes,
+		&i.Login.Username, &i.Login.Password, &i.Login.Totp,
+		&c.CardholderName, &c.Brand, &c.Number, &c.ExpMonth, &c.ExpYear, &c.Code,
+		&id.Title, &id.FirstName, &id.MiddleName, &id.LastName,
+		&id.Address1, &id.Address2, &id.Address3, &id.City, &id.State, &id.PostalCode, &id.Country,
+		&id.Company, &id.Email, &id.Phone, &id.Ssn, &id.Username, &id.PassportNumber, &id.LicenseNumber,
+	}
+	for j := range i.Login.Uris {
+		s = append(s, &i.Login.Uris[j].Uri)
+	}
+	for j := range i.Fields {
+		s = append(s, &i.Fields[j].Name, &i.Fields[j].Value)
+	}
+	for j := range i.PasswordHistory {
+		s = append(s, &i.PasswordHistory[j].Password)
+	}
+	for j := range i.Attachments {
+		s = append(s, &i.Attachments[j].FileName)
+	}
+	return s
+}
+
+// cipherKey is the key an item of vault is encrypted with: its own, its
+// organization's or the user's.
+func (a *API) cipherKey(vault *apiVault, meta cipherMeta) (symmetricKey, error) {
+	key := *a.key
+	if meta.OrganizationId != "" {
+		orgKey, ok := vault.orgKeys[meta.OrganizationId]
+		if !ok {
+			return symmetricKey{}, ErrDecrypt
+		}
+		key = orgKey
+	}
+	if meta.Key != "" {
+		return key.decryptKey(meta.Key)
+	}
+	return key, nil
+}
+
+// undecryptable is the name of items that can't be decrypted, as the
+// Bitwarden clients show them.
+const undecryptable = "[error: cannot decrypt]"
+
+func (a *API) decryptCipher(vault *apiVault, raw json.RawMessage) (Item, cipherMeta, error) {
+	var item Item
+	var meta cipherMeta
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return item, meta, fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return item, meta, fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	// the rest of the cipher is encrypted junk to the TUI
+	item.extra = nil
+	key, err := a.cipherKey(vault, meta)
+	if err == nil {
+		for _, s := range itemStrings(&item) {
+			if *s, err = key.decryptString(*s); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		item = Item{Id: item.Id, Type: item.Type, FolderId: item.FolderId, Name: undecryptable, DeletedDate: item.DeletedDate}
+	}
+	return item, meta, nil
+}
+
+// cipherRequest is an item as the server takes it when creating or
+// editing one.
+type cipherRequest struct {
+	Type                  int               `json:"type"`
+	OrganizationId        string            `json:"organizationId,omitempty"`
+	FolderId              string            `json:"folderId,omitempty"`
+	Key                   string            `json:"key,omitempty"`
+	Name                  string            `json:"name"`
+	Notes                 string            `json:"notes,omitempty"`
+	Favorite              bool              `json:"favorite"`
+	Reprompt              int               `json:"reprompt"`
+	Login                 *Login            `json:"login,omitempty"`
+	Card                  *Card             `json:"card,omitempty"`
+	Identity              *Identity         `json:"identity,omitempty"`
+	SecureNote            *SecureNote       `json:"secureNote,omitempty"`
+	Fields                []Field           `json:"fields,omitempty"`
+	PasswordHistory       []PasswordHistory `json:"passwordHistory,omitempty"`
+	LastKnownRevisionDate *time.Time        `json:"lastKnownRevisionDate,omitempty"`
+}
+
+// encryptCipher encrypts a copy of item as the request to save it.
+func (a *API) encryptCipher(item Item, meta cipherMeta) (json.RawMessage, error) {
+	key, err := a.cipherKey(&a.vault, meta)
+	if err != nil {
+		return nil, err
+	}
+	// the slices are shared with the caller's item
+	item.Login.Uris = append([]Uri(nil), item.Login.Uris...)
+	item.Fields = append([]Field(nil), item.Fields...)
+	item.PasswordHistory = append([]PasswordHistory(nil), item.PasswordHistory...)
+	for j := range item.Login.Uris {
+		// the checksum of the old URI would no longer match
+		item.Login.Uris[j].extra = without(item.Login.Uris[j].extra, "uriChecksum")
+	}
+	for _, s := range itemStrings(&item) {
+		if *s, err = key.encryptString(*s); err != nil {
+			return nil, err
+		}
+	}
+	req := cipherRequest{
+		Type:                  item.Type,
+		OrganizationId:        meta.OrganizationId,
+		FolderId:              item.FolderId,
+		Key:                   meta.Key,
+		Name:                  item.Name,
+		Notes:                 item.Notes,
+		Favorite:              item.Favorite,
+		Reprompt:              meta.Reprompt,
+		Fields:                item.Fields,
+		PasswordHistory:       item.PasswordHistory,
+		LastKnownRevisionDate: meta.RevisionDate,
+	}
+	switch item.Type {
+	case TypeLogin:
+		req.Login = &item.Login
+	case TypeCard:
+		req.Card = &item.Card
+	case TypeIdentity:
+		req.Identity = &item.Identity
+	case TypeSecureNote:
+		req.SecureNote = &item.SecureNote
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	// the server wants null rather than empty cipher strings
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(nullEmpty(v))
+}
+
+func without(extra extraFields, name string) extraFields {
+	if _, ok := extra[name]; !ok {
+		return extra
+	}
+	copied := extraFields{}
+	for k, v := range extra {
+		if k != name {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+func nullEmpty(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case map[string]interface{}:
+		for k, member := range v {
+			v[k] = nullEmpty(member)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = nullEmpty(element)
+		}
+	}
+	return v
+}
+
+// saveCipher sends a cipher request and keeps the item the server answers.
+func (a *API) saveCipher(ctx context.Context, method, path string, item Item, meta cipherMeta) (*Item, error) {
+	req, err := a.encryptCipher(item, meta)
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := a.call(ctx, method, path, req, &raw); err != nil {
+		return nil, err
+	}
+	saved, savedMeta, err := a.decryptCipher(&a.vault, raw)
+	if err != nil {
+		return nil, err
+	}
+	a.vault.ciphers[saved.Id] = savedMeta
+	if i := a.item(saved.Id); i != nil {
+		*i = saved
+	} else {
+		a.vault.items = append(a.vault.items, saved)
+	}
+	return &saved, nil
+}
+
+func (a *API) item(id string) *Item {
+	for i := range a.vault.items {
+		if a.vault.items[i].Id == id {
+			return &a.vault.items[i]
+		}
+	}
+	return nil
+}
+
+func (a *API) GetItems(ctx context.Context, filter FilterOptions) ([]Item, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	return Filter(a.vault.items, filter.matches), nil
+}
+
+func (a *API) GetItem(ctx context.Context, id string) (*Item, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	i := a.item(id)
+	if i == nil {
+		return nil, ErrNotFound
+	}
+	item := *i
+	return &item, nil
+}
+
+func (a *API) GetFolder(ctx context.Context, id string) (*Folder, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	for _, f := range a.vault.folders {
+		if f.Id == id {
+			folder := f
+			return &folder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (a *API) GetFolders(ctx context.Context) ([]Folder, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	return append([]Folder(nil), a.vault.folders...), nil
+}
+
+func (a *API) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	return a.saveFolder(ctx, http.MethodPost, "/folders", name)
+}
+
+func (a *API) EditFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	return a.saveFolder(ctx, http.MethodPut, "/folders/"+folder.Id, folder.Name)
+}
+
+func (a *API) saveFolder(ctx context.Context, method, path, name string) (*Folder, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	encrypted, err := a.key.encryptString(name)
+	if err != nil {
+		return nil, err
+	}
+	var saved Folder
+	if err := a.call(ctx, method, path, map[string]string{"name": encrypted}, &saved); err != nil {
+		return nil, err
+	}
+	saved.Name = name
+	for i := range a.vault.folders {
+		if a.vault.folders[i].Id == saved.Id {
+			a.vault.folders[i] = saved
+			return &saved, nil
+		}
+	}
+	a.vault.folders = append(a.vault.folders, saved)
+	return &saved, nil
+}
+
+// DeleteFolder deletes a folder; the server moves its items out of any
+// folder.
+func (a *API) DeleteFolder(ctx context.Context, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	if err := a.call(ctx, http.MethodDelete, "/folders/"+id, nil, nil); err != nil {
+		return err
+	}
+	for i := range a.vault.folders {
+		if a.vault.folders[i].Id == id {
+			a.vault.folders = append(a.vault.folders[:i], a.vault.folders[i+1:]...)
+			break
+		}
+	}
+	for i := range a.vault.items {
+		if a.vault.items[i].FolderId == id {
+			a.vault.items[i].FolderId = ""
+		}
+	}
+	return nil
+}
+
+// CreateItem creates a personal item, encrypted with the user's key.
+func (a *API) CreateItem(ctx context.Context, item Item) (*Item, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	return a.saveCipher(ctx, http.MethodPost, "/ciphers", item, cipherMeta{})
+}
+
+// EditItem saves an item with the key it was encrypted with. A changed
+// password goes to the password history, as the Bitwarden clients do it.
+func (a *API) EditItem(ctx context.Context, item Item) (*Item, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	current := a.item(item.Id)
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	if old := current.Login.Password; old != "" && old != item.Login.Password {
+		now := time.Now()
+		item.PasswordHistory = append([]PasswordHistory{{LastUsedDate: &now, Password: old}}, item.PasswordHistory...)
+		if len(item.PasswordHistory) > 5 {
+			item.PasswordHistory = item.PasswordHistory[:5]
+		}
+		item.Login.PasswordRevisionDate = &now
+	}
+	return a.saveCipher(ctx, http.MethodPut, "/ciphers/"+item.Id, item, a.vault.ciphers[item.Id])
+}
+
+// DeleteItem moves an item to the trash, or purges it for good when
+// permanent is set.
+func (a *API) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	if permanent {
+		if err := a.call(ctx, http.MethodDelete, "/ciphers/"+id, nil, nil); err != nil {
+			return err
+		}
+		for i := range a.vault.items {
+			if a.vault.items[i].Id == id {
+				a.vault.items = append(a.vault.items[:i], a.vault.items[i+1:]...)
+				break
+			}
+		}
+		delete(a.vault.ciphers, id)
+		return nil
+	}
+	if err := a.call(ctx, http.MethodPut, "/ciphers/"+id+"/delete", nil, nil); err != nil {
+		return err
+	}
+	if i := a.item(id); i != nil {
+		now := time.Now()
+		i.DeletedDate = &now
+	}
+	return nil
+}
+
+func (a *API) RestoreItem(ctx context.Context, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	if err := a.call(ctx, http.MethodPut, "/ciphers/"+id+"/restore", nil, nil); err != nil {
+		return err
+	}
+	if i := a.item(id); i != nil {
+		i.DeletedDate = nil
+	}
+	return nil
+}
+
+func (a *API) GetTrash(ctx context.Context) ([]Item, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	return Filter(a.vault.items, func(i Item) bool {
+		return i.DeletedDate != nil
+	}), nil
+}
+
+// DownloadAttachment fetches an attachment from where the server says it
+// is stored and decrypts it to path.
+func (a *API) DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	var attachment struct {
+		Url string `json:"url"`
+		Key string `json:"key"`
+	}
+	if err := a.call(ctx, http.MethodGet, "/ciphers/"+itemId+"/attachment/"+attachmentId, nil, &attachment); err != nil {
+		return err
+	}
+	key, err := a.cipherKey(&a.vault, a.vault.ciphers[itemId])
+	if err != nil {
+		return err
+	}
+	if attachment.Key != "" {
+		if key, err = key.decryptKey(attachment.Key); err != nil {
+			return err
+		}
+	}
+	link := attachment.Url
+	if strings.HasPrefix(link, "/") {
+		link = a.server() + link
+	}
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client().Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	if resp.StatusCode >= 400 {
+		return responseError(resp.StatusCode, data)
+	}
+	plain, err := key.decryptFile(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, plain, 0600)
+}
+
+func (a *API) UploadAttachment(ctx context.Context, itemId, path string) (*Item, error) {
+	return nil, ErrUnsupported
+}
+
+func (a *API) DeleteAttachment(ctx context.Context, itemId, attachmentId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	if err := a.call(ctx, http.MethodDelete, "/ciphers/"+itemId+"/attachment/"+attachmentId, nil, nil); err != nil {
+		return err
+	}
+	if i := a.item(itemId); i != nil {
+		for j, attachment := range i.Attachments {
+			if attachment.Id == attachmentId {
+				i.Attachments = append(i.Attachments[:j:j], i.Attachments[j+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// decryptSend decrypts a send with the key derived from its link's
+// material, which the user's key encrypts.
+func (a *API) decryptSend(raw json.RawMessage) (Send, error) {
+	var send Send
+	var meta struct {
+		Key      string  `json:"key"`
+		Password *string `json:"password"`
+	}
+	if err := json.Unmarshal(raw, &send); err != nil {
+		return send, fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return send, fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	send.extra = nil
+	// password is a hash of it, only telling that there is one
+	send.Password = ""
+	send.PasswordSet = meta.Password != nil
+	material, err := a.key.decrypt(meta.Key)
+	if err == nil {
+		var key symmetricKey
+		if key, err = sendKey(material); err == nil {
+			for _, s := range []*string{&send.Name, &send.Notes, &send.Text.Text, &send.File.FileName} {
+				if *s, err = key.decryptString(*s); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		return Send{Id: send.Id, AccessId: send.AccessId, Type: send.Type, Name: undecryptable}, nil
+	}
+	send.AccessUrl = a.webVaultUrl() + "/#/send/" + send.AccessId + "/" + base64.RawURLEncoding.EncodeToString(material)
+	return send, nil
+}
+
+func (a *API) GetSends(ctx context.Context) ([]Send, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return nil, ErrLocked
+	}
+	return append([]Send(nil), a.vault.sends...), nil
+}
+
+func (a *API) CreateSend(ctx context.Context, send Send) (*Send, error) {
+	return nil, ErrUnsupported
+}
+
+func (a *API) EditSend(ctx context.Context, send Send) (*Send, error) {
+	return nil, ErrUnsupported
+}
+
+func (a *API) DeleteSend(ctx context.Context, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.key == nil {
+		return ErrLocked
+	}
+	if err := a.call(ctx, http.MethodDelete, "/sends/"+id, nil, nil); err != nil {
+		return err
+	}
+	for i := range a.vault.sends {
+		if a.vault.sends[i].Id == id {
+			a.vault.sends = append(a.vault.sends[:i], a.vault.sends[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -1,34 +1,253 @@
 package backend
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+var (
+	ErrNotLoggedIn       = errors.New("not logged in")
+	ErrLocked            = errors.New("vault is locked")
+	ErrInvalidPassword   = errors.New("invalid master password")
+	ErrNotFound          = errors.New("not found")
+	ErrTwoFactorRequired = errors.New("two-step login required")
+	ErrInvalidTwoFactor  = errors.New("invalid two-step login code")
+	ErrNetwork           = errors.New("network unavailable")
+	ErrCLIMissing        = errors.New("bw CLI not found")
+	ErrUnexpectedOutput  = errors.New("unexpected output from bw")
+)
+
+// Authentication states reported by Status.
+const (
+	StatusUnauthenticated = "unauthenticated"
+	StatusLocked          = "locked"
+	StatusUnlocked        = "unlocked"
+)
+
+type Status struct {
+	ServerUrl string     `json:"serverUrl"`
+	LastSync  *time.Time `json:"lastSync"`
+	UserEmail string     `json:"userEmail"`
+	UserId    string     `json:"userId"`
+	Status    string     `json:"status"`
+}
+
+// ServerConfig is the server a vault belongs to. Api, Identity and
+// WebVault are for self-hosted setups that don't serve them under Url;
+// empty, they are derived from it.
+type ServerConfig struct {
+	Url      string
+	Api      string
+	Identity string
+	WebVault string
+}
+
+// SameServer tells whether two server URLs are the same server, the empty
+// one being Bitwarden's cloud.
+func SameServer(a, b string) bool {
+	normalize := func(url string) string {
+		url = strings.ToLower(strings.TrimRight(strings.TrimSpace(url), "/"))
+		if url == "" || url == "https://bitwarden.com" {
+			// what bw reports for the cloud, which is served by the web vault
+			return DefaultServer
+		}
+		return url
+	}
+	return normalize(a) == normalize(b)
+}
+
+// TwoFactorMethod is a two-step login provider, numbered as bw login's
+// --method expects.
+type TwoFactorMethod int
+
+const (
+	TwoFactorAuthenticator TwoFactorMethod = 0
+	TwoFactorEmail         TwoFactorMethod = 1
+	TwoFactorYubiKey       TwoFactorMethod = 3
+)
+
+// TwoFactor answers a two-step login challenge. An empty Code with the
+// email method asks the server to send one.
+type TwoFactor struct {
+	Method TwoFactorMethod
+	Code   string
+}
+
+// Vault is everything the TUI needs from a Bitwarden vault. Context talks
+// to the bw CLI; Memory keeps the vault in-process for tests and demos.
+// Every call gives up, killing bw if need be, once its ctx is done.
+type Vault interface {
+	Status(ctx context.Context) (*Status, error)
+	// Login logs in and unlocks. twoFactor is nil on the first attempt;
+	// ErrTwoFactorRequired asks for another one with it set.
+	Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error
+	// LoginAPIKey logs in with a personal API key, leaving the vault locked.
+	LoginAPIKey(ctx context.Context, clientId, clientSecret string) error
+	Unlock(ctx context.Context, password string) error
+	// Lock forgets the session, a password is needed to unlock again.
+	Lock(ctx context.Context) error
+	GetItems(ctx context.Context, filter FilterOptions) ([]Item, error)
+	GetItem(ctx context.Context, id string) (*Item, error)
+	GetFolder(ctx context.Context, id string) (*Folder, error)
+	GetFolders(ctx context.Context) ([]Folder, error)
+	CreateFolder(ctx context.Context, name string) (*Folder, error)
+	EditFolder(ctx context.Context, folder Folder) (*Folder, error)
+	DeleteFolder(ctx context.Context, id string) error
+	CreateItem(ctx context.Context, item Item) (*Item, error)
+	EditItem(ctx context.Context, item Item) (*Item, error)
+	DeleteItem(ctx context.Context, id string, permanent bool) error
+	RestoreItem(ctx context.Context, id string) error
+	GetTrash(ctx context.Context) ([]Item, error)
+	// DownloadAttachment saves an attachment of an item to path.
+	DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error
+	// UploadAttachment attaches the file at path and returns the item.
+	UploadAttachment(ctx context.Context, itemId, path string) (*Item, error)
+	DeleteAttachment(ctx context.Context, itemId, attachmentId string) error
+	Sync(ctx context.Context) error
+	// LastSync is when the vault last synced with the server, nil if never.
+	LastSync(ctx context.Context) (*time.Time, error)
+	GetServer(ctx context.Context) (*ServerConfig, error)
+	// SetServer switches to another server, logging out of the current one.
+	SetServer(ctx context.Context, server ServerConfig) error
+	GetSends(ctx context.Context) ([]Send, error)
+	// CreateSend creates a text send, or a file send of the file at
+	// File.FileName.
+	CreateSend(ctx context.Context, send Send) (*Send, error)
+	EditSend(ctx context.Context, send Send) (*Send, error)
+	DeleteSend(ctx context.Context, id string) error
+}
+
+// Context is a Vault backed by the bw CLI.
 type Context struct {
 	SessionKey string
+	// Binary is the bw executable to run, "bw" from PATH by default.
+	Binary string
+	// AppDataDir is where bw keeps its login, server and vault, the
+	// directory of bw's own choosing when empty.
+	AppDataDir string
 }
 
 type Uri struct {
 	Uri string `json:"uri"`
+
+	extra extraFields
 }
 
+// Item types as numbered by Bitwarden.
+const (
+	TypeLogin      = 1
+	TypeSecureNote = 2
+	TypeCard       = 3
+	TypeIdentity   = 4
+)
+
 type Login struct {
 	Uris     []Uri  `json:"uris"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Totp is the authenticator secret, see the totp package
+	Totp string `json:"totp"`
+	// PasswordRevisionDate is when the password last changed
+	PasswordRevisionDate *time.Time `json:"passwordRevisionDate"`
+
+	extra extraFields
+}
+
+type Card struct {
+	CardholderName string `json:"cardholderName"`
+	Brand          string `json:"brand"`
+	Number         string `json:"number"`
+	ExpMonth       string `json:"expMonth"`
+	ExpYear        string `json:"expYear"`
+	Code           string `json:"code"`
+
+	extra extraFields
+}
+
+type Identity struct {
+	Title          string `json:"title"`
+	FirstName      string `json:"firstName"`
+	MiddleName     string `json:"middleName"`
+	LastName       string `json:"lastName"`
+	Address1       string `json:"address1"`
+	Address2       string `json:"address2"`
+	Address3       string `json:"address3"`
+	City           string `json:"city"`
+	State          string `json:"state"`
+	PostalCode     string `json:"postalCode"`
+	Country        string `json:"country"`
+	Company        string `json:"company"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	Ssn            string `json:"ssn"`
+	Username       string `json:"username"`
+	PassportNumber string `json:"passportNumber"`
+	LicenseNumber  string `json:"licenseNumber"`
+
+	extra extraFields
+}
+
+// FullName joins the non-empty name parts of the identity.
+func (i Identity) FullName() string {
+	return joinNonEmpty(" ", i.Title, i.FirstName, i.MiddleName, i.LastName)
+}
+
+type SecureNote struct {
+	Type int `json:"type"` // generic - 0
+
+	extra extraFields
 }
 
 type Item struct {
-	Id       string  `json:"id"`
-	FolderId string  `json:"folderId"`
-	Type     int     `json:"type"`
-	Name     string  `json:"name"`
-	Notes    string  `json:"notes,omitempty"`
-	Favorite bool    `json:"favorite"`
-	Fields   []Field `json:"fields"`
-	Login    Login   `json:"login"`
+	Id         string     `json:"id"`
+	FolderId   string     `json:"folderId"`
+	Type       int        `json:"type"`
+	Name       string     `json:"name"`
+	Notes      string     `json:"notes,omitempty"`
+	Favorite   bool       `json:"favorite"`
+	Fields     []Field    `json:"fields"`
+	Login      Login      `json:"login"`
+	Card       Card       `json:"card"`
+	Identity   Identity   `json:"identity"`
+	SecureNote SecureNote `json:"secureNote"`
+	// DeletedDate is set for items in the trash
+	DeletedDate *time.Time   `json:"deletedDate"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// PasswordHistory lists earlier passwords, newest first
+	PasswordHistory []PasswordHistory `json:"passwordHistory,omitempty"`
+
+	// members bw returned that aren't modelled above, see extraFields
+	extra extraFields
+}
+
+// PasswordHistory is a password an item had before.
+type PasswordHistory struct {
+	// LastUsedDate is when the password was replaced
+	LastUsedDate *time.Time `json:"lastUsedDate"`
+	Password     string     `json:"password"`
+
+	extra extraFields
+}
+
+// Attachment is a file stored with an item. Its contents are only fetched
+// by DownloadAttachment.
+type Attachment struct {
+	Id       string `json:"id"`
+	FileName string `json:"fileName"`
+	// Size is in bytes, SizeName the same for people, like "1.2 MB"
+	Size     string `json:"size"`
+	SizeName string `json:"sizeName"`
+	Url      string `json:"url"`
+
+	extra extraFields
 }
 
 type Folder struct {
@@ -39,85 +258,496 @@ type Folder struct {
 type FilterOptions struct {
 	Search string
 	Url    string
+	// FolderId limits the items to one folder, "null" for those in none.
+	FolderId string
 }
 
 type Field struct {
 	Name     string `json:"name"`
 	Value    string `json:"value"`
-	Type     int8   `json:"type"`   // text - 0, hidden - 1, boolean - 2
-	LinkedId int    `json:"linkedId"`
+	Type     int8   `json:"type"`     // text - 0, hidden - 1, boolean - 2
+	LinkedId *int   `json:"linkedId"` // only set for linked fields
+
+	extra extraFields
 }
 
-func (c *Context) exec(args ...string) ([]byte, error) {
-	cmd := exec.Command("bw", args...)
+var (
+	_ Vault = (*Context)(nil)
+	_ Vault = (*Memory)(nil)
+)
+
+// NewContext picks up the session of a vault already unlocked in the
+// shell through BW_SESSION.
+func NewContext() *Context {
+	return &Context{SessionKey: os.Getenv("BW_SESSION"), Binary: "bw"}
+}
+
+// passwordEnv is the variable the master password is handed to bw in, so
+// that it never appears in the child's argv.
+const passwordEnv = "BWTUI_PASSWORD"
+
+func (c *Context) exec(ctx context.Context, args ...string) ([]byte, error) {
+	return c.run(ctx, nil, nil, args...)
+}
+
+// run executes bw with env appended to the current environment and stdin
+// piped in. Secrets go through here rather than args, which any user can
+// read from ps.
+func (c *Context) run(ctx context.Context, env []string, stdin []byte, args ...string) ([]byte, error) {
+	binary := c.Binary
+	if binary == "" {
+		binary = "bw"
+	}
+	// the process is killed once ctx is done
+	cmd := exec.CommandContext(ctx, binary, args...)
+	// the session and data directory are this vault's, whatever the
+	// environment holds for other ones
+	cmd.Env = append(os.Environ(), "BW_SESSION="+c.SessionKey)
+	if c.AppDataDir != "" {
+		cmd.Env = append(cmd.Env, "BITWARDENCLI_APPDATA_DIR="+c.AppDataDir)
+	}
+	cmd.Env = append(cmd.Env, env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
 	output, err := cmd.Output()
-	return output, err
+	if err != nil {
+		return nil, commandError(ctx, args, err, c.secrets(env))
+	}
+	return output, nil
 }
 
-func InitializeClient(password string) (*Context, error) {
-	ctx := &Context{}
-	key, err := ctx.exec("unlock", "--raw", password)
+// secrets are the values stderr must not show: the session and whatever
+// went through the environment.
+func (c *Context) secrets(env []string) []string {
+	secrets := []string{c.SessionKey}
+	for _, kv := range env {
+		if i := strings.Index(kv, "="); i >= 0 {
+			secrets = append(secrets, kv[i+1:])
+		}
+	}
+	return secrets
+}
+
+// encode runs a JSON payload through bw encode, which is the format create
+// and edit expect their payload in, as an argument or on stdin.
+func (c *Context) encode(ctx context.Context, payload []byte) (string, error) {
+	output, err := c.run(ctx, nil, payload, "encode")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func InitializeClient(ctx context.Context, password string) (*Context, error) {
+	c := NewContext()
+	if err := c.Unlock(ctx, password); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Context) Status(ctx context.Context) (*Status, error) {
+	output, err := c.exec(ctx, "status")
 	if err != nil {
 		return nil, err
 	}
-	ctx.SessionKey = string(key)
-	err = os.Setenv("BW_SESSION", ctx.SessionKey)
+	var status *Status
+	err = decodeOutput(output, &status)
 	if err != nil {
 		return nil, err
 	}
-	return ctx, nil
+	return status, nil
 }
 
-func (c *Context) GetItems(filter FilterOptions) ([]Item, error) {
-	output, err := c.exec("list", "items", "--search", filter.Search, "--url", filter.Url)
+func (c *Context) Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error {
+	args := []string{"login", email, "--raw", "--passwordenv", passwordEnv}
+	if twoFactor != nil {
+		args = append(args, "--method", strconv.Itoa(int(twoFactor.Method)))
+		if twoFactor.Code != "" {
+			// bw has no --passwordenv for the code, so it goes in argv. It
+			// expires within minutes and is no use without the password.
+			args = append(args, "--code", twoFactor.Code)
+		}
+	}
+	key, err := c.run(ctx, []string{passwordEnv + "=" + password}, nil, args...)
+	if err != nil {
+		return loginError(err)
+	}
+	c.SetSession(string(key))
+	return nil
+}
+
+func (c *Context) LoginAPIKey(ctx context.Context, clientId, clientSecret string) error {
+	_, err := c.run(ctx,
+		[]string{"BW_CLIENTID=" + clientId, "BW_CLIENTSECRET=" + clientSecret}, nil,
+		"login", "--apikey",
+	)
+	return err
+}
+
+func (c *Context) Unlock(ctx context.Context, password string) error {
+	key, err := c.run(ctx,
+		[]string{passwordEnv + "=" + password}, nil,
+		"unlock", "--raw", "--passwordenv", passwordEnv,
+	)
+	if err != nil {
+		return err
+	}
+	c.SetSession(string(key))
+	return nil
+}
+
+func (c *Context) Lock(ctx context.Context) error {
+	_, err := c.exec(ctx, "lock")
+	c.SessionKey = ""
+	return err
+}
+
+// GetServer asks bw for the server's URL. bw config server prints nothing
+// else, so the other URLs are read from bw's data.json.
+func (c *Context) GetServer(ctx context.Context) (*ServerConfig, error) {
+	output, err := c.exec(ctx, "config", "server")
+	if err != nil {
+		return nil, err
+	}
+	server := readEnvironment(c.AppDataDir)
+	server.Url = strings.TrimSpace(string(output))
+	return &server, nil
+}
+
+// readEnvironment reads the server URLs bw config server set from the
+// data.json in appDataDir, bw's default directory when empty. It's the
+// zero ServerConfig when there is no such file, or none of its layouts
+// match.
+func readEnvironment(appDataDir string) ServerConfig {
+	if appDataDir == "" {
+		appDataDir = os.Getenv("BITWARDENCLI_APPDATA_DIR")
+	}
+	if appDataDir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return ServerConfig{}
+		}
+		appDataDir = filepath.Join(config, "Bitwarden CLI")
+	}
+	data, err := os.ReadFile(filepath.Join(appDataDir, "data.json"))
+	if err != nil {
+		return ServerConfig{}
+	}
+	type urls struct {
+		Base     string `json:"base"`
+		Api      string `json:"api"`
+		Identity string `json:"identity"`
+		WebVault string `json:"webVault"`
+	}
+	var state map[string]json.RawMessage
+	if json.Unmarshal(data, &state) != nil {
+		return ServerConfig{}
+	}
+	var found *urls
+	// bw 2024 and later keep it per account, and for when logged out
+	var active string
+	json.Unmarshal(state["global_account_activeAccountId"], &active)
+	for _, key := range []string{"user_" + active + "_environment_environment", "global_environment_environment"} {
+		var environment struct {
+			Urls *urls `json:"urls"`
+		}
+		if json.Unmarshal(state[key], &environment) == nil && environment.Urls != nil {
+			found = environment.Urls
+			break
+		}
+	}
+	// before, in global, and at the top before that
+	if found == nil {
+		var global struct {
+			EnvironmentUrls *urls `json:"environmentUrls"`
+		}
+		if json.Unmarshal(state["global"], &global) == nil && global.EnvironmentUrls != nil {
+			found = global.EnvironmentUrls
+		} else {
+			json.Unmarshal(state["environmentUrls"], &found)
+		}
+	}
+	if found == nil {
+		return ServerConfig{}
+	}
+	return ServerConfig{Url: found.Base, Api: found.Api, Identity: found.Identity, WebVault: found.WebVault}
+}
+
+// SetServer logs out first, as bw refuses to change the server of a
+// login.
+func (c *Context) SetServer(ctx context.Context, server ServerConfig) error {
+	if _, err := c.exec(ctx, "logout"); err != nil && !errors.Is(err, ErrNotLoggedIn) {
+		return err
+	}
+	c.SessionKey = ""
+	// null goes back to the cloud
+	args := []string{"config", "server", "null"}
+	if server.Url != "" {
+		args[2] = server.Url
+	}
+	for _, url := range []struct{ flag, value string }{
+		{"--api", server.Api},
+		{"--identity", server.Identity},
+		{"--web-vault", server.WebVault},
+	} {
+		if url.value != "" {
+			args = append(args, url.flag, url.value)
+		}
+	}
+	_, err := c.exec(ctx, args...)
+	return err
+}
+
+// SetSession makes bw use an existing session key, as bw unlock --raw
+// prints it.
+func (c *Context) SetSession(key string) {
+	c.SessionKey = strings.TrimSpace(key)
+}
+
+// loginError tells apart the bw login failures the login screen reacts to.
+// Without a terminal bw can't prompt for a two-step code and fails instead.
+func loginError(err error) error {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return err
+	}
+	switch {
+	case strings.Contains(cmdErr.Stderr, "Code is required"),
+		strings.Contains(cmdErr.Stderr, "No provider selected"):
+		cmdErr.Err = ErrTwoFactorRequired
+	case strings.Contains(cmdErr.Stderr, "Two-step token is invalid"):
+		cmdErr.Err = ErrInvalidTwoFactor
+	case strings.Contains(cmdErr.Stderr, "Username or password is incorrect"):
+		cmdErr.Err = ErrInvalidPassword
+	}
+	return cmdErr
+}
+
+func (c *Context) GetItems(ctx context.Context, filter FilterOptions) ([]Item, error) {
+	args := []string{"list", "items", "--search", filter.Search, "--url", filter.Url}
+	if filter.FolderId != "" {
+		args = append(args, "--folderid", filter.FolderId)
+	}
+	output, err := c.exec(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
 	var items []Item
-	err = json.Unmarshal(output, &items)
+	err = decodeOutput(output, &items)
 	if err != nil {
 		return nil, err
 	}
-	items = Filter(items, func(i Item) bool {
-		return i.Type == 1
-	})
 	return items, nil
 }
 
-func (c *Context) GetItem(id string) (*Item, error) {
-	output, err := c.exec("get", "item", id)
+func (c *Context) GetItem(ctx context.Context, id string) (*Item, error) {
+	output, err := c.exec(ctx, "get", "item", id)
 	if err != nil {
 		return nil, err
 	}
 	var item *Item
-	err = json.Unmarshal(output, &item)
+	err = decodeOutput(output, &item)
 	if err != nil {
 		return nil, err
 	}
 	return item, nil
 }
 
-func (c *Context) GetFolder(id string) (*Folder, error) {
-	output, err := c.exec("get", "folder", id)
+func (c *Context) GetFolder(ctx context.Context, id string) (*Folder, error) {
+	output, err := c.exec(ctx, "get", "folder", id)
 	if err != nil {
 		return nil, err
 	}
 	var folder *Folder
-	err = json.Unmarshal(output, &folder)
+	err = decodeOutput(output, &folder)
 	if err != nil {
 		return nil, err
 	}
 	return folder, nil
 }
 
-func (c *Context) Sync() error {
-	_, err := c.exec("sync")
+func (c *Context) GetFolders(ctx context.Context) ([]Folder, error) {
+	output, err := c.exec(ctx, "list", "folders")
+	if err != nil {
+		return nil, err
+	}
+	var folders []Folder
+	err = decodeOutput(output, &folders)
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (c *Context) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	return c.saveFolder(ctx, Folder{Name: name}, "create", "folder")
+}
+
+func (c *Context) EditFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	return c.saveFolder(ctx, folder, "edit", "folder", folder.Id)
+}
+
+func (c *Context) saveFolder(ctx context.Context, folder Folder, args ...string) (*Folder, error) {
+	payload, err := json.Marshal(struct {
+		Name string `json:"name"`
+	}{folder.Name})
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := c.encode(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.exec(ctx, append(args, encoded)...)
+	if err != nil {
+		return nil, err
+	}
+	var saved *Folder
+	err = decodeOutput(output, &saved)
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (c *Context) DeleteFolder(ctx context.Context, id string) error {
+	_, err := c.exec(ctx, "delete", "folder", id)
+	return err
+}
+
+// CreateItem fills bw's item template with item and creates it. Template
+// keys item has no say in (organization, reprompt, ...) keep their defaults.
+func (c *Context) CreateItem(ctx context.Context, item Item) (*Item, error) {
+	template, err := c.exec(ctx, "get", "template", "item")
+	if err != nil {
+		return nil, err
+	}
+	payload, err := templatePayload(template, item)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := c.encode(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	// piped in, as the item's secrets would show in ps as an argument
+	output, err := c.run(ctx, nil, []byte(encoded), "create", "item")
+	if err != nil {
+		return nil, err
+	}
+	var created *Item
+	err = decodeOutput(output, &created)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// EditItem replaces the stored item with the same id. Members of the
+// original JSON that Item doesn't model are sent back as they were.
+func (c *Context) EditItem(ctx context.Context, item Item) (*Item, error) {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := c.encode(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.run(ctx, nil, []byte(encoded), "edit", "item", item.Id)
+	if err != nil {
+		return nil, err
+	}
+	var edited *Item
+	err = decodeOutput(output, &edited)
+	if err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+// DeleteItem moves an item to the trash, or purges it for good when
+// permanent is set.
+func (c *Context) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	args := []string{"delete", "item", id}
+	if permanent {
+		args = append(args, "--permanent")
+	}
+	_, err := c.exec(ctx, args...)
+	return err
+}
+
+func (c *Context) RestoreItem(ctx context.Context, id string) error {
+	_, err := c.exec(ctx, "restore", "item", id)
+	return err
+}
+
+func (c *Context) GetTrash(ctx context.Context) ([]Item, error) {
+	output, err := c.exec(ctx, "list", "items", "--trash")
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	err = decodeOutput(output, &items)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (c *Context) DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error {
+	_, err := c.exec(ctx, "get", "attachment", attachmentId, "--itemid", itemId, "--output", path)
+	if err != nil {
+		return err
+	}
+	// bw creates the file readable by other users under the usual umask
+	return os.Chmod(path, 0600)
+}
+
+func (c *Context) UploadAttachment(ctx context.Context, itemId, path string) (*Item, error) {
+	output, err := c.exec(ctx, "create", "attachment", "--file", path, "--itemid", itemId)
+	if err != nil {
+		return nil, err
+	}
+	var item *Item
+	err = decodeOutput(output, &item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (c *Context) DeleteAttachment(ctx context.Context, itemId, attachmentId string) error {
+	_, err := c.exec(ctx, "delete", "attachment", attachmentId, "--itemid", itemId)
+	return err
+}
+
+func (c *Context) Sync(ctx context.Context) error {
+	_, err := c.exec(ctx, "sync")
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (c *Context) LastSync(ctx context.Context) (*time.Time, error) {
+	output, err := c.exec(ctx, "sync", "--last")
+	if err != nil {
+		return nil, err
+	}
+	last := strings.TrimSpace(string(output))
+	if last == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, last)
+	if err != nil {
+		return nil, ErrUnexpectedOutput
+	}
+	return &t, nil
+}
+
 func Filter(vs []Item, f func(Item) bool) []Item {
 	filtered := make([]Item, 0)
 	for _, v := range vs {
@@ -135,3 +765,38 @@ func Map(vs []Field, f func(Field) string) []string {
 	}
 	return mapped
 }
+
+func joinNonEmpty(sep string, parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// templatePayload overlays an item or send onto a bw template. Members the
+// template has and v doesn't set, like the other types' sub-objects, stay
+// as they are.
+func templatePayload(template []byte, v interface{}) ([]byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(template, &payload); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		if k == "id" {
+			continue
+		}
+		payload[k] = v
+	}
+	return json.Marshal(payload)
+}
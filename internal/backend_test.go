@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeBW puts a bw first in PATH that prints output, and records its
+// argv, environment and stdin in the directory returned.
+func fakeBW(t *testing.T, output string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake bw is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" >> '" + dir + "/argv'\n" +
+		"env > '" + dir + "/env'\n" +
+		"cat > '" + dir + "/stdin'\n" +
+		"printf '%s' '" + output + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, "bw"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func readRecorded(t *testing.T, dir, name string) string {
+	t.Helper()
+	return string(readFile(t, filepath.Join(dir, name)))
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGetServerReadsDataJSON(t *testing.T) {
+	layouts := map[string]string{
+		"2024": `{
+			"global_account_activeAccountId": "u1",
+			"global_environment_environment": {"region": "Self-hosted", "urls": {"base": "https://old.example.com"}},
+			"user_u1_environment_environment": {"region": "Self-hosted", "urls": {
+				"base": "https://vault.example.com",
+				"api": "https://api.example.com",
+				"identity": "https://identity.example.com",
+				"webVault": "https://web.example.com"
+			}}
+		}`,
+		"global": `{"global": {"environmentUrls": {
+			"base": "https://vault.example.com",
+			"api": "https://api.example.com",
+			"identity": "https://identity.example.com",
+			"webVault": "https://web.example.com"
+		}}}`,
+		"top level": `{"environmentUrls": {
+			"base": "https://vault.example.com",
+			"api": "https://api.example.com",
+			"identity": "https://identity.example.com",
+			"webVault": "https://web.example.com"
+		}}`,
+	}
+	want := ServerConfig{
+		Url:      "https://vault.example.com",
+		Api:      "https://api.example.com",
+		Identity: "https://identity.example.com",
+		WebVault: "https://web.example.com",
+	}
+	for name, layout := range layouts {
+		t.Run(name, func(t *testing.T) {
+			dir := fakeBW(t, "https://vault.example.com\n")
+			appData := t.TempDir()
+			if err := os.WriteFile(filepath.Join(appData, "data.json"), []byte(layout), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c := &Context{Binary: "bw", AppDataDir: appData}
+			server, err := c.GetServer(context.Background())
+			if err != nil {
+				t.Fatalf("GetServer: %v", err)
+			}
+			if *server != want {
+				t.Errorf("GetServer = %+v, want %+v", *server, want)
+			}
+			if argv := readRecorded(t, dir, "argv"); argv != "config\nserver\n" {
+				t.Errorf("argv = %q", argv)
+			}
+		})
+	}
+}
+
+func TestGetServerWithoutDataJSON(t *testing.T) {
+	fakeBW(t, "https://vault.example.com")
+	c := &Context{Binary: "bw", AppDataDir: t.TempDir()}
+	server, err := c.GetServer(context.Background())
+	if err != nil {
+		t.Fatalf("GetServer: %v", err)
+	}
+	if want := (ServerConfig{Url: "https://vault.example.com"}); *server != want {
+		t.Errorf("GetServer = %+v, want %+v", *server, want)
+	}
+}
+
+func TestPasswordStaysOutOfArgv(t *testing.T) {
+	const password = "correct horse battery"
+	ctx := context.Background()
+
+	dir := fakeBW(t, "SESSIONKEY")
+	c := &Context{Binary: "bw"}
+	if err := c.Unlock(ctx, password); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if c.SessionKey != "SESSIONKEY" {
+		t.Errorf("session = %q after unlocking", c.SessionKey)
+	}
+	checkPasswordPassed(t, dir, password)
+
+	dir = fakeBW(t, "SESSIONKEY")
+	c = &Context{Binary: "bw"}
+	if err := c.Login(ctx, "user@example.com", password, &TwoFactor{Method: TwoFactorAuthenticator, Code: "123456"}); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	checkPasswordPassed(t, dir, password)
+}
+
+// checkPasswordPassed fails unless bw got password in passwordEnv, and
+// nowhere in its argv.
+func checkPasswordPassed(t *testing.T, dir, password string) {
+	t.Helper()
+	argv := readRecorded(t, dir, "argv")
+	if strings.Contains(argv, password) {
+		t.Errorf("password in argv %q", argv)
+	}
+	if !strings.Contains(argv, "--passwordenv\n"+passwordEnv+"\n") {
+		t.Errorf("argv %q doesn't point bw to %s", argv, passwordEnv)
+	}
+	env := readRecorded(t, dir, "env")
+	if !strings.Contains(env, "\n"+passwordEnv+"="+password+"\n") {
+		t.Errorf("%s isn't set to the password", passwordEnv)
+	}
+}
+
+func TestItemSecretsStayOutOfArgv(t *testing.T) {
+	dir := fakeBW(t, `{"id": "i1", "type": 1, "name": "GitHub", "login": {"password": "hunter2"}}`)
+	c := &Context{Binary: "bw", SessionKey: "SESSIONKEY"}
+	item := Item{Type: TypeLogin, Name: "GitHub", Login: Login{Password: "hunter2"}}
+	if _, err := c.CreateItem(context.Background(), item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	item.Id = "i1"
+	if _, err := c.EditItem(context.Background(), item); err != nil {
+		t.Fatalf("EditItem: %v", err)
+	}
+	if argv := readRecorded(t, dir, "argv"); strings.Contains(argv, "hunter2") || strings.Contains(argv, "SESSIONKEY") {
+		t.Errorf("secrets in argv %q", argv)
+	}
+	// the encoded item went in on stdin
+	if stdin := readRecorded(t, dir, "stdin"); !strings.Contains(stdin, "hunter2") {
+		t.Errorf("stdin = %q, want the item", stdin)
+	}
+}
+
+func TestDownloadAttachmentIsPrivate(t *testing.T) {
+	dir := fakeBW(t, "")
+	path := filepath.Join(t.TempDir(), "recovery.txt")
+	// the fake bw writes nothing, so stand in for the file it would create
+	if err := os.WriteFile(path, []byte("1234 5678"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Context{Binary: "bw", SessionKey: "SESSIONKEY"}
+	if err := c.DownloadAttachment(context.Background(), "i1", "a1", path); err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	if argv := readRecorded(t, dir, "argv"); argv != "get\nattachment\na1\n--itemid\ni1\n--output\n"+path+"\n" {
+		t.Errorf("argv = %q", argv)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("attachment has mode %o, want 600", mode)
+	}
+}
+
+func TestFailedUnlockHidesPassword(t *testing.T) {
+	const password = "correct horse battery"
+	dir := fakeBW(t, "")
+	script := filepath.Join(dir, "bw")
+	// a bw that repeats the password in its complaint
+	failing := "echo \"Invalid master password: $" + passwordEnv + "\" >&2\nexit 1\n"
+	if err := os.WriteFile(script, append(readFile(t, script), failing...), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	c := &Context{Binary: "bw"}
+	err := c.Unlock(context.Background(), password)
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("Unlock = %v, want ErrInvalidPassword", err)
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || strings.Contains(cmdErr.Stderr, password) || strings.Contains(err.Error(), password) {
+		t.Errorf("the error shows the password: %v, %+v", err, cmdErr)
+	}
+}
@@ -0,0 +1,456 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrOffline is a change, or a read the cache can't answer, asked of a vault
+// that was opened from its cache because the server couldn't be reached.
+var ErrOffline = errors.New("vault is offline")
+
+// cacheKdf is how the key of the cache is derived from the master password.
+var cacheKdf = KdfConfig{Kdf: KdfArgon2id, Iterations: 3, Memory: 64, Parallelism: 4}
+
+// Cache is a Vault that keeps an encrypted copy of the items and folders of
+// another one in a file. The copy is keyed from the master password, so it
+// can be shown as soon as the vault unlocks, and still be read when the
+// vault itself can't be reached.
+type Cache struct {
+	Vault
+	// Path is the file the copy is kept in.
+	Path string
+
+	mu       sync.Mutex
+	email    string
+	file     *cacheFile
+	key      *symmetricKey
+	snapshot *cacheSnapshot
+	offline  bool
+}
+
+// cacheFile is what's written to Path. Data is the cacheSnapshot as JSON,
+// encrypted with the key derived from the master password and Salt.
+type cacheFile struct {
+	Email string    `json:"email"`
+	Salt  []byte    `json:"salt"`
+	Kdf   KdfConfig `json:"kdf"`
+	Data  string    `json:"data"`
+}
+
+type cacheSnapshot struct {
+	Items   []Item    `json:"items"`
+	Folders []Folder  `json:"folders"`
+	Updated time.Time `json:"updated"`
+}
+
+func NewCache(vault Vault, path string) *Cache {
+	return &Cache{Vault: vault, Path: path}
+}
+
+// DefaultCachePath is where the cache goes unless told otherwise, "" when
+// the system has no place for caches.
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bwtui", "vault.cache")
+}
+
+// Items are the cached items, available right after unlocking. ok is false
+// when nothing was cached yet.
+func (c *Cache) Items(filter FilterOptions) (items []Item, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshot == nil {
+		return nil, false
+	}
+	return Filter(c.snapshot.Items, filter.matches), true
+}
+
+// Offline tells whether the vault was opened from the cache alone, which
+// makes it read-only.
+func (c *Cache) Offline() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offline
+}
+
+// Close closes the vault underneath when it holds on to something, like
+// the bw serve a Serve started.
+func (c *Cache) Close() error {
+	if closer, ok := c.Vault.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Status falls back to the cache when the vault can't tell, so that it can
+// still be unlocked.
+func (c *Cache) Status(ctx context.Context) (*Status, error) {
+	status, err := c.Vault.Status(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.email = status.UserEmail
+		return status, nil
+	}
+	if !unreachable(err) || ctx.Err() != nil {
+		return nil, err
+	}
+	file, readErr := c.read()
+	if readErr != nil {
+		return nil, err
+	}
+	return &Status{UserEmail: file.Email, Status: StatusLocked}, nil
+}
+
+func (c *Cache) Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error {
+	if err := c.Vault.Login(ctx, email, password, twoFactor); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.email = email
+	return c.open(password)
+}
+
+// Unlock opens the cache along with the vault. When the vault can't be
+// reached, the cache alone is opened, read-only.
+func (c *Cache) Unlock(ctx context.Context, password string) error {
+	err := c.Vault.Unlock(ctx, password)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		return c.open(password)
+	}
+	if !unreachable(err) || ctx.Err() != nil {
+		return err
+	}
+	if c.decrypt(password) != nil {
+		// the password can't be checked, so don't tell it's wrong
+		return err
+	}
+	c.offline = true
+	return nil
+}
+
+// open decrypts the cache with the master password, starting it over when
+// it's missing, doesn't decrypt or belongs to another account.
+func (c *Cache) open(password string) error {
+	c.offline = false
+	err := c.decrypt(password)
+	if err == nil && (c.email == "" || strings.EqualFold(c.file.Email, c.email)) {
+		return nil
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := cacheKey(password, salt, cacheKdf)
+	if err != nil {
+		return err
+	}
+	c.file = &cacheFile{Email: c.email, Salt: salt, Kdf: cacheKdf}
+	c.key = &key
+	c.snapshot = nil
+	return nil
+}
+
+// decrypt reads the cache and decrypts it with the master password.
+func (c *Cache) decrypt(password string) error {
+	file, err := c.read()
+	if err != nil {
+		return err
+	}
+	key, err := cacheKey(password, file.Salt, file.Kdf)
+	if err != nil {
+		return err
+	}
+	data, err := key.decrypt(file.Data)
+	if err != nil {
+		return err
+	}
+	var snapshot cacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	c.file, c.key, c.snapshot = file, &key, &snapshot
+	return nil
+}
+
+func (c *Cache) Lock(ctx context.Context) error {
+	c.mu.Lock()
+	c.file, c.key, c.snapshot = nil, nil, nil
+	c.offline = false
+	c.mu.Unlock()
+	return c.Vault.Lock(ctx)
+}
+
+// GetItems always gets every item, to keep all of them cached, and filters
+// them here.
+func (c *Cache) GetItems(ctx context.Context, filter FilterOptions) ([]Item, error) {
+	if c.Offline() {
+		items, _ := c.Items(filter)
+		return items, nil
+	}
+	items, err := c.Vault.GetItems(ctx, FilterOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c.update(func(s *cacheSnapshot) { s.Items = items })
+	return Filter(items, filter.matches), nil
+}
+
+func (c *Cache) GetItem(ctx context.Context, id string) (*Item, error) {
+	c.mu.Lock()
+	if !c.offline {
+		c.mu.Unlock()
+		return c.Vault.GetItem(ctx, id)
+	}
+	defer c.mu.Unlock()
+	for _, i := range c.snapshot.Items {
+		if i.Id == id {
+			item := i
+			return &item, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (c *Cache) GetFolder(ctx context.Context, id string) (*Folder, error) {
+	c.mu.Lock()
+	if !c.offline {
+		c.mu.Unlock()
+		return c.Vault.GetFolder(ctx, id)
+	}
+	defer c.mu.Unlock()
+	for _, f := range c.snapshot.Folders {
+		if f.Id == id {
+			folder := f
+			return &folder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (c *Cache) GetFolders(ctx context.Context) ([]Folder, error) {
+	c.mu.Lock()
+	if c.offline {
+		defer c.mu.Unlock()
+		return append([]Folder(nil), c.snapshot.Folders...), nil
+	}
+	c.mu.Unlock()
+	folders, err := c.Vault.GetFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.update(func(s *cacheSnapshot) { s.Folders = folders })
+	return folders, nil
+}
+
+func (c *Cache) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.CreateFolder(ctx, name)
+}
+
+func (c *Cache) EditFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.EditFolder(ctx, folder)
+}
+
+func (c *Cache) DeleteFolder(ctx context.Context, id string) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.DeleteFolder(ctx, id)
+}
+
+func (c *Cache) CreateItem(ctx context.Context, item Item) (*Item, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.CreateItem(ctx, item)
+}
+
+func (c *Cache) EditItem(ctx context.Context, item Item) (*Item, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.EditItem(ctx, item)
+}
+
+func (c *Cache) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.DeleteItem(ctx, id, permanent)
+}
+
+func (c *Cache) RestoreItem(ctx context.Context, id string) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.RestoreItem(ctx, id)
+}
+
+func (c *Cache) GetTrash(ctx context.Context) ([]Item, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.GetTrash(ctx)
+}
+
+func (c *Cache) DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.DownloadAttachment(ctx, itemId, attachmentId, path)
+}
+
+func (c *Cache) UploadAttachment(ctx context.Context, itemId, path string) (*Item, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.UploadAttachment(ctx, itemId, path)
+}
+
+func (c *Cache) DeleteAttachment(ctx context.Context, itemId, attachmentId string) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.DeleteAttachment(ctx, itemId, attachmentId)
+}
+
+func (c *Cache) Sync(ctx context.Context) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.Sync(ctx)
+}
+
+func (c *Cache) GetSends(ctx context.Context) ([]Send, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.GetSends(ctx)
+}
+
+func (c *Cache) CreateSend(ctx context.Context, send Send) (*Send, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.CreateSend(ctx, send)
+}
+
+func (c *Cache) EditSend(ctx context.Context, send Send) (*Send, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.Vault.EditSend(ctx, send)
+}
+
+func (c *Cache) DeleteSend(ctx context.Context, id string) error {
+	if c.Offline() {
+		return ErrOffline
+	}
+	return c.Vault.DeleteSend(ctx, id)
+}
+
+// update changes the cached copy and writes it out. The cache is only a
+// convenience, so failing to write it isn't an error.
+func (c *Cache) update(change func(*cacheSnapshot)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key == nil {
+		// unlocked by a session, without the password to key the cache with
+		return
+	}
+	if c.snapshot == nil {
+		c.snapshot = &cacheSnapshot{}
+	}
+	change(c.snapshot)
+	c.snapshot.Updated = time.Now()
+	_ = c.write()
+}
+
+func (c *Cache) read() (*cacheFile, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// write replaces the file through a rename, so that it is never left half
+// written.
+func (c *Cache) write() error {
+	plain, err := json.Marshal(c.snapshot)
+	if err != nil {
+		return err
+	}
+	file := *c.file
+	if file.Data, err = c.key.encrypt(plain); err != nil {
+		return err
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".vault-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// cacheKey derives the key of the cache from the master password.
+func cacheKey(password string, salt []byte, kdf KdfConfig) (symmetricKey, error) {
+	if kdf.Kdf != KdfArgon2id || kdf.Iterations <= 0 || kdf.Memory <= 0 || kdf.Parallelism <= 0 {
+		return symmetricKey{}, errors.New("unsupported cache KDF")
+	}
+	key := argon2.IDKey([]byte(password), salt, uint32(kdf.Iterations),
+		uint32(kdf.Memory)*1024, uint8(kdf.Parallelism), 64)
+	return newSymmetricKey(key)
+}
+
+// unreachable tells whether err means the vault couldn't be reached, as
+// opposed to turning a request down.
+func unreachable(err error) bool {
+	var serverErr *serverError
+	if errors.As(err, &serverErr) {
+		return serverErr.StatusCode >= 500
+	}
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrCLIMissing)
+}
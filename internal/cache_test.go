@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// unreachableVault is a vault whose server is down.
+type unreachableVault struct{ Vault }
+
+func (unreachableVault) Status(ctx context.Context) (*Status, error) { return nil, ErrNetwork }
+func (unreachableVault) Unlock(ctx context.Context, password string) error {
+	return ErrNetwork
+}
+
+// newCachedVault unlocks a vault in memory through a Cache at path, which
+// writes the cache.
+func newCachedVault(t *testing.T, path string) *Cache {
+	t.Helper()
+	vault := NewMemory("hunter2", []Item{{
+		Id:    "i1",
+		Type:  TypeLogin,
+		Name:  "GitHub",
+		Login: Login{Username: "octo", Password: "p4ssw0rd"},
+	}}, []Folder{{Id: "f1", Name: "Work"}})
+	vault.Email = "user@example.com"
+	cache := NewCache(vault, path)
+	ctx := context.Background()
+	if _, err := cache.Status(ctx); err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if err := cache.Unlock(ctx, "hunter2"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := cache.GetItems(ctx, FilterOptions{}); err != nil {
+		t.Fatalf("GetItems: %v", err)
+	}
+	if _, err := cache.GetFolders(ctx); err != nil {
+		t.Fatalf("GetFolders: %v", err)
+	}
+	if err := cache.Lock(ctx); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	return cache
+}
+
+// offline is a Cache of path over a vault that can't be reached.
+func offline(path string) *Cache {
+	return NewCache(unreachableVault{NewMemory("", nil, nil)}, path)
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.cache")
+	newCachedVault(t, path)
+
+	cache := offline(path)
+	ctx := context.Background()
+	status, err := cache.Status(ctx)
+	if err != nil || status.UserEmail != "user@example.com" || status.Status != StatusLocked {
+		t.Fatalf("Status = %+v, %v, want locked from the cache", status, err)
+	}
+	if err := cache.Unlock(ctx, "hunter2"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if !cache.Offline() {
+		t.Error("a cache opened without its vault isn't offline")
+	}
+	items, err := cache.GetItems(ctx, FilterOptions{})
+	if err != nil || len(items) != 1 || items[0].Login.Password != "p4ssw0rd" {
+		t.Errorf("GetItems = %+v, %v", items, err)
+	}
+	folders, err := cache.GetFolders(ctx)
+	if err != nil || len(folders) != 1 || folders[0].Name != "Work" {
+		t.Errorf("GetFolders = %+v, %v", folders, err)
+	}
+	if _, err := cache.CreateFolder(ctx, "Home"); !errors.Is(err, ErrOffline) {
+		t.Errorf("CreateFolder offline = %v, want ErrOffline", err)
+	}
+}
+
+func TestCacheWrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.cache")
+	newCachedVault(t, path)
+
+	cache := offline(path)
+	err := cache.Unlock(context.Background(), "hunter3")
+	// the password can't be checked against the vault, the network is blamed
+	if !errors.Is(err, ErrNetwork) {
+		t.Errorf("Unlock = %v, want ErrNetwork", err)
+	}
+	if _, ok := cache.Items(FilterOptions{}); ok || cache.Offline() {
+		t.Error("the cache opened with the wrong password")
+	}
+}
+
+func TestCacheTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.cache")
+	newCachedVault(t, path)
+
+	var file cacheFile
+	if err := json.Unmarshal(readFile(t, path), &file); err != nil {
+		t.Fatal(err)
+	}
+	// flip a bit of the ciphertext, leaving the MAC as it was
+	parts := strings.Split(file.Data, "|")
+	if len(parts) != 3 {
+		t.Fatalf("data = %q, want iv|ciphertext|mac", file.Data)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext[0] ^= 1
+	parts[1] = base64.StdEncoding.EncodeToString(ciphertext)
+	file.Data = strings.Join(parts, "|")
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := offline(path)
+	if err := cache.Unlock(context.Background(), "hunter2"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Unlock = %v, want ErrNetwork", err)
+	}
+	if _, ok := cache.Items(FilterOptions{}); ok {
+		t.Error("a tampered cache opened")
+	}
+}
+
+func TestCacheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "vault.cache")
+	newCachedVault(t, path)
+
+	data := readFile(t, path)
+	for _, secret := range []string{"p4ssw0rd", "octo", "GitHub", "Work", "hunter2"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the cache file holds %q in the clear", secret)
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("cache file mode = %v, want 0600", mode)
+	}
+	info, err = os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o700 {
+		t.Errorf("cache directory mode = %v, want 0700", mode)
+	}
+}
@@ -0,0 +1,108 @@
+// Package clipboard copies secrets and takes them back out of the clipboard
+// after a while, putting back whatever was there before.
+package clipboard
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTimeout is how long a copied secret stays in the clipboard.
+const DefaultTimeout = 30 * time.Second
+
+// ErrUnreadable is returned by backends that can only write, like OSC 52.
+var ErrUnreadable = errors.New("clipboard can't be read")
+
+// Backend is a way to get at a clipboard.
+type Backend interface {
+	Name() string
+	Read() (string, error)
+	// Write puts s in the clipboard. An empty s clears it.
+	Write(s string) error
+}
+
+// Guard remembers what it copied and what it replaced. Nothing is restored
+// once something else has been copied, so the user's own copies survive.
+// When the backend can't be read the copy is simply cleared.
+type Guard struct {
+	// Backend is nil when no clipboard is available
+	Backend Backend
+	// Timeout is how long copies are kept, 0 to keep them
+	Timeout time.Duration
+
+	copied   string
+	previous string
+	// clearAt is zero while nothing is waiting to be cleared
+	clearAt time.Time
+}
+
+func NewGuard(backend Backend, timeout time.Duration) *Guard {
+	return &Guard{Backend: backend, Timeout: timeout}
+}
+
+// Unsupported reports whether there is no clipboard to copy to.
+func (g *Guard) Unsupported() bool {
+	return g.Backend == nil
+}
+
+// Copy puts s in the clipboard. Copying again before the clear keeps the
+// contents from before the first copy to restore.
+func (g *Guard) Copy(s string) error {
+	if g.Backend == nil {
+		return errors.New("no clipboard available")
+	}
+	if !g.Pending() || !g.holdsCopy() {
+		// an empty or unreadable clipboard is restored as empty
+		g.previous, _ = g.Backend.Read()
+	}
+	if err := g.Backend.Write(s); err != nil {
+		return err
+	}
+	g.copied = s
+	if g.Timeout > 0 {
+		g.clearAt = time.Now().Add(g.Timeout)
+	}
+	return nil
+}
+
+// Pending reports whether a copy is waiting to be cleared.
+func (g *Guard) Pending() bool {
+	return !g.clearAt.IsZero()
+}
+
+// Remaining is the time left until the copy should be cleared, 0 or less
+// once it is due.
+func (g *Guard) Remaining(now time.Time) time.Duration {
+	if !g.Pending() {
+		return 0
+	}
+	return g.clearAt.Sub(now)
+}
+
+// Clear puts back the previous contents if the clipboard still holds the
+// copy. It does nothing when no copy is pending.
+func (g *Guard) Clear() error {
+	if !g.Pending() {
+		return nil
+	}
+	defer g.forget()
+	current, err := g.Backend.Read()
+	switch {
+	case errors.Is(err, ErrUnreadable):
+		return g.Backend.Write("")
+	case err != nil || current != g.copied:
+		return nil
+	}
+	return g.Backend.Write(g.previous)
+}
+
+func (g *Guard) holdsCopy() bool {
+	current, err := g.Backend.Read()
+	return err == nil && current == g.copied
+}
+
+func (g *Guard) forget() {
+	g.copied = ""
+	g.previous = ""
+	g.clearAt = time.Time{}
+}
@@ -0,0 +1,133 @@
+package clipboard
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestGuardWithoutClipboard(t *testing.T) {
+	guard := NewGuard(nil, DefaultTimeout)
+	if !guard.Unsupported() {
+		t.Error("a guard without a backend claims a clipboard")
+	}
+	if err := guard.Copy("hunter2"); err == nil {
+		t.Error("copying without a clipboard didn't fail")
+	}
+	if guard.Pending() {
+		t.Error("a failed copy is waiting to be cleared")
+	}
+}
+
+func TestGuardTimeout(t *testing.T) {
+	command, _ := fakeBoard(t, "before")
+	guard := NewGuard(command, time.Minute)
+	if err := guard.Copy("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if remaining := guard.Remaining(now); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("Remaining = %v right after copying", remaining)
+	}
+	if remaining := guard.Remaining(now.Add(2 * time.Minute)); remaining > 0 {
+		t.Errorf("Remaining = %v after the timeout", remaining)
+	}
+
+	// no timeout keeps copies
+	guard = NewGuard(command, 0)
+	if err := guard.Copy("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if guard.Pending() {
+		t.Error("a copy without a timeout is waiting to be cleared")
+	}
+}
+
+func TestGuardClearsUnreadable(t *testing.T) {
+	for _, name := range []string{"TMUX", "STY", "TERM"} {
+		t.Setenv(name, "")
+	}
+	var out bytes.Buffer
+	guard := NewGuard(OSC52{Out: &out}, DefaultTimeout)
+	if err := guard.Copy("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := guard.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	// what was there before can't be known, so the clipboard is emptied
+	if out.String() != osc52Sequence("") {
+		t.Errorf("cleared with %q, want an empty copy", out.String())
+	}
+	if guard.Pending() {
+		t.Error("still pending after clearing")
+	}
+}
+
+// memBoard is a clipboard in memory.
+type memBoard struct {
+	contents string
+}
+
+func (b *memBoard) Name() string { return "memory" }
+
+func (b *memBoard) Read() (string, error) { return b.contents, nil }
+
+func (b *memBoard) Write(s string) error {
+	b.contents = s
+	return nil
+}
+
+func TestGuardRestores(t *testing.T) {
+	board := &memBoard{contents: "before"}
+	guard := NewGuard(board, DefaultTimeout)
+	if err := guard.Copy("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if board.contents != "hunter2" {
+		t.Fatalf("clipboard holds %q after copying", board.contents)
+	}
+	if err := guard.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if board.contents != "before" {
+		t.Errorf("clipboard holds %q after clearing, want what was there before", board.contents)
+	}
+	if guard.Pending() {
+		t.Error("still pending after clearing")
+	}
+}
+
+func TestGuardKeepsNewerCopies(t *testing.T) {
+	board := &memBoard{contents: "before"}
+	guard := NewGuard(board, DefaultTimeout)
+	if err := guard.Copy("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	// the user copies something of their own
+	board.contents = "mine"
+	if err := guard.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if board.contents != "mine" {
+		t.Errorf("clipboard holds %q after clearing, want the user's copy", board.contents)
+	}
+}
+
+func TestGuardCopyingTwice(t *testing.T) {
+	board := &memBoard{contents: "before"}
+	guard := NewGuard(board, DefaultTimeout)
+	for _, s := range []string{"alice", "hunter2"} {
+		if err := guard.Copy(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := guard.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	// not the first copy, which the second replaced
+	if board.contents != "before" {
+		t.Errorf("clipboard holds %q after clearing, want what was there before both copies", board.contents)
+	}
+}
@@ -0,0 +1,84 @@
+package clipboard
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Command runs programs to copy and paste, the copied text going through
+// stdin and the pasted text coming from stdout.
+type Command struct {
+	Label string
+	Copy  []string
+	// Paste is nil when the clipboard can't be read back
+	Paste []string
+	// Clear replaces Copy when emptying the clipboard, if set
+	Clear []string
+	// Secret are arguments added to Copy to mark the text as a password,
+	// so clipboard history tools skip it. Copying is retried without them
+	// when the program doesn't know them. Only wl-copy has such a flag:
+	// xclip and xsel offer the text under one type, leaving no room for
+	// the hint types KDE and macOS history tools look for.
+	Secret []string
+}
+
+func (c Command) Name() string { return c.Label }
+
+func (c Command) Read() (string, error) {
+	if len(c.Paste) == 0 {
+		return "", ErrUnreadable
+	}
+	output, err := exec.Command(c.Paste[0], c.Paste[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+func (c Command) Write(s string) error {
+	if s == "" && len(c.Clear) > 0 {
+		return exec.Command(c.Clear[0], c.Clear[1:]...).Run()
+	}
+	if len(c.Secret) > 0 && s != "" {
+		if err := c.run(append(append([]string(nil), c.Copy...), c.Secret...), s); err == nil {
+			return nil
+		}
+	}
+	return c.run(c.Copy, s)
+}
+
+func (c Command) run(args []string, s string) error {
+	if len(args) == 0 {
+		return errors.New("no clipboard command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(s)
+	return cmd.Run()
+}
+
+// UserCommand copies with a command line from the configuration, split on
+// spaces. It can't read the clipboard back.
+func UserCommand(command string) Command {
+	return Command{Label: "command", Copy: strings.Fields(command)}
+}
+
+var (
+	WlCopy = Command{
+		Label:  "wl-copy",
+		Copy:   []string{"wl-copy"},
+		Paste:  []string{"wl-paste", "--no-newline"},
+		Clear:  []string{"wl-copy", "--clear"},
+		Secret: []string{"--sensitive"},
+	}
+	Xclip = Command{
+		Label: "xclip",
+		Copy:  []string{"xclip", "-in", "-selection", "clipboard"},
+		Paste: []string{"xclip", "-out", "-selection", "clipboard"},
+	}
+	Xsel = Command{
+		Label: "xsel",
+		Copy:  []string{"xsel", "--input", "--clipboard"},
+		Paste: []string{"xsel", "--output", "--clipboard"},
+	}
+)
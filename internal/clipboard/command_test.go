@@ -0,0 +1,88 @@
+package clipboard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeBoard is a clipboard in a file, with a copy and a paste program for
+// it. The copy program records its arguments next to it.
+func fakeBoard(t *testing.T, contents string) (Command, string) {
+	t.Helper()
+	dir := fakeCommands(t, map[string]string{
+		"copy":  `printf '%s\n' "$@" > "${0%/*}/args"; "$CAT" > "${0%/*}/board"`,
+		"paste": `"$CAT" "${0%/*}/board"`,
+	})
+	board := filepath.Join(dir, "board")
+	if err := os.WriteFile(board, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Command{Label: "fake", Copy: []string{"copy"}, Paste: []string{"paste"}}, board
+}
+
+func readBoard(t *testing.T, board string) string {
+	t.Helper()
+	data, err := os.ReadFile(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCommandCopiesAndPastes(t *testing.T) {
+	command, board := fakeBoard(t, "before")
+	if got, err := command.Read(); err != nil || got != "before" {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	if err := command.Write("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBoard(t, board); got != "hunter2" {
+		t.Errorf("board = %q after writing", got)
+	}
+}
+
+func TestCommandSecretHint(t *testing.T) {
+	command, board := fakeBoard(t, "")
+	command.Secret = []string{"--sensitive"}
+	if err := command.Write("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if args := readBoard(t, filepath.Join(filepath.Dir(board), "args")); args != "--sensitive\n" {
+		t.Errorf("copied with args %q, want the hint", args)
+	}
+
+	// a copy program too old for the hint
+	dir := fakeCommands(t, map[string]string{
+		"copy": `[ "$1" = --sensitive ] && exit 1; "$CAT" > "${0%/*}/board"`,
+	})
+	command = Command{Label: "fake", Copy: []string{"copy"}, Secret: []string{"--sensitive"}}
+	if err := command.Write("hunter2"); err != nil {
+		t.Fatalf("Write without the hint: %v", err)
+	}
+	if got := readBoard(t, filepath.Join(dir, "board")); got != "hunter2" {
+		t.Errorf("board = %q after writing without the hint", got)
+	}
+}
+
+func TestCommandClear(t *testing.T) {
+	command, board := fakeBoard(t, "hunter2")
+	command.Clear = []string{"copy", "--clear"}
+	if err := command.Write(""); err != nil {
+		t.Fatal(err)
+	}
+	if args := readBoard(t, filepath.Join(filepath.Dir(board), "args")); args != "--clear\n" {
+		t.Errorf("cleared with args %q", args)
+	}
+}
+
+func TestUserCommandCantPaste(t *testing.T) {
+	command := UserCommand("pbcopy -pboard general")
+	if len(command.Copy) != 3 || command.Copy[0] != "pbcopy" {
+		t.Errorf("Copy = %q", command.Copy)
+	}
+	if _, err := command.Read(); err != ErrUnreadable {
+		t.Errorf("Read = %v, want ErrUnreadable", err)
+	}
+}
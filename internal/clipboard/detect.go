@@ -0,0 +1,77 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/atotto/clipboard"
+)
+
+// System is the clipboard github.com/atotto/clipboard finds, for macOS and
+// Windows and whatever else it knows.
+type System struct{}
+
+func (System) Name() string { return "system" }
+
+func (System) Read() (string, error) {
+	return clipboard.ReadAll()
+}
+
+func (System) Write(s string) error {
+	return clipboard.WriteAll(s)
+}
+
+// New returns the backend called name: osc52, wl-copy, xclip, xsel, system
+// or command, which runs command. An empty name or auto picks one.
+func New(name, command string) (Backend, error) {
+	switch name {
+	case "", "auto":
+		return Detect(command), nil
+	case "osc52":
+		return OSC52{}, nil
+	case "wl-copy":
+		return WlCopy, nil
+	case "xclip":
+		return Xclip, nil
+	case "xsel":
+		return Xsel, nil
+	case "system":
+		return System{}, nil
+	case "command":
+		if command == "" {
+			return nil, fmt.Errorf("the command clipboard needs a command")
+		}
+		return UserCommand(command), nil
+	}
+	return nil, fmt.Errorf("unknown clipboard %q", name)
+}
+
+// Detect picks the configured command, then the clipboard of the local
+// Wayland or X session, then the system one. OSC 52 is the fallback, and
+// it comes first in SSH sessions without a forwarded display.
+func Detect(command string) Backend {
+	if command != "" {
+		return UserCommand(command)
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" && installed("wl-copy") {
+		return WlCopy
+	}
+	if os.Getenv("DISPLAY") != "" {
+		if installed("xclip") {
+			return Xclip
+		}
+		if installed("xsel") {
+			return Xsel
+		}
+	}
+	if os.Getenv("SSH_TTY") == "" && os.Getenv("SSH_CONNECTION") == "" && !clipboard.Unsupported {
+		return System{}
+	}
+	return OSC52{}
+}
+
+func installed(program string) bool {
+	_, err := exec.LookPath(program)
+	return err == nil
+}
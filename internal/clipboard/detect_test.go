@@ -0,0 +1,94 @@
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/atotto/clipboard"
+)
+
+// fakeCommands makes a directory of shell scripts, named after the keys of
+// scripts, the whole of PATH. Scripts can run cat through $CAT.
+func fakeCommands(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake commands are shell scripts")
+	}
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("no cat for the fake commands")
+	}
+	dir := t.TempDir()
+	for name, script := range scripts {
+		script = "#!/bin/sh\nCAT='" + cat + "'\n" + script + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	// atotto/clipboard looks for its programs once, at start
+	local := "system"
+	if clipboard.Unsupported {
+		local = "osc52"
+	}
+	tests := []struct {
+		name      string
+		command   string
+		env       map[string]string
+		installed string
+		want      string
+	}{
+		{"command first", "pbcopy", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, "wl-copy xclip xsel", "command"},
+		{"wayland", "", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, "wl-copy xclip xsel", "wl-copy"},
+		{"xwayland", "", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, "xclip", "xclip"},
+		{"xclip before xsel", "", map[string]string{"DISPLAY": ":0"}, "xclip xsel", "xclip"},
+		{"xsel", "", map[string]string{"DISPLAY": ":0"}, "xsel", "xsel"},
+		{"wl-copy needs wayland", "", map[string]string{"DISPLAY": ":0"}, "wl-copy xsel", "xsel"},
+		{"nothing installed", "", map[string]string{"DISPLAY": ":0"}, "", local},
+		{"no display", "", nil, "wl-copy xclip xsel", local},
+		{"ssh", "", map[string]string{"SSH_TTY": "/dev/pts/1"}, "xclip", "osc52"},
+		{"ssh connection", "", map[string]string{"SSH_CONNECTION": "10.0.0.1 50000 10.0.0.2 22"}, "", "osc52"},
+		{"ssh with forwarded X", "", map[string]string{"SSH_TTY": "/dev/pts/1", "DISPLAY": "localhost:10.0"}, "xclip", "xclip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripts := map[string]string{}
+			for _, name := range strings.Fields(tt.installed) {
+				scripts[name] = "exit 0"
+			}
+			fakeCommands(t, scripts)
+			for _, name := range []string{"WAYLAND_DISPLAY", "DISPLAY", "SSH_TTY", "SSH_CONNECTION"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := Detect(tt.command).Name(); got != tt.want {
+				t.Errorf("Detect = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"osc52", "wl-copy", "xclip", "xsel", "system"} {
+		backend, err := New(name, "")
+		if err != nil || backend.Name() != name {
+			t.Errorf("New(%q) = %v, %v", name, backend, err)
+		}
+	}
+	if backend, err := New("command", "pbcopy"); err != nil || backend.Name() != "command" {
+		t.Errorf("New(command) = %v, %v", backend, err)
+	}
+	if _, err := New("command", ""); err == nil {
+		t.Error("New(command) without a command didn't fail")
+	}
+	if _, err := New("klipper", ""); err == nil {
+		t.Error("New(klipper) didn't fail")
+	}
+}
@@ -0,0 +1,60 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+)
+
+// screenChunk is the longest string screen passes through in one piece.
+const screenChunk = 76
+
+// OSC52 asks the terminal to set the clipboard with an escape sequence, so
+// copying works over SSH. The sequence is wrapped for tmux and screen,
+// which otherwise swallow it. The clipboard can't be read back.
+type OSC52 struct {
+	// Out is the terminal, /dev/tty when nil
+	Out io.Writer
+}
+
+func (o OSC52) Name() string { return "osc52" }
+
+func (o OSC52) Read() (string, error) {
+	return "", ErrUnreadable
+}
+
+func (o OSC52) Write(s string) error {
+	out := o.Out
+	if out == nil {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer tty.Close()
+		out = tty
+	}
+	_, err := io.WriteString(out, osc52Sequence(s))
+	return err
+}
+
+func osc52Sequence(s string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(s)) + "\x07"
+	switch {
+	case os.Getenv("TMUX") != "":
+		// tmux passes on DCS contents with every escape doubled
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case os.Getenv("STY") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := screenChunk
+			if n > len(seq) {
+				n = len(seq)
+			}
+			b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	}
+	return seq
+}
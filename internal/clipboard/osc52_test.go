@@ -0,0 +1,74 @@
+package clipboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOSC52Sequence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"plain", nil, "\x1b]52;c;aHVudGVyMg==\x07"},
+		{"tmux", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"}, "\x1bPtmux;\x1b\x1b]52;c;aHVudGVyMg==\x07\x1b\\"},
+		{"screen", map[string]string{"STY": "1.pts-0"}, "\x1bP\x1b]52;c;aHVudGVyMg==\x07\x1b\\"},
+		{"screen TERM", map[string]string{"TERM": "screen-256color"}, "\x1bP\x1b]52;c;aHVudGVyMg==\x07\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"TMUX", "STY", "TERM"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := osc52Sequence("hunter2"); got != tt.want {
+				t.Errorf("osc52Sequence = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSC52SequenceScreenChunks(t *testing.T) {
+	for _, name := range []string{"TMUX", "STY", "TERM"} {
+		t.Setenv(name, "")
+	}
+	s := strings.Repeat("correct horse battery staple ", 10)
+	plain := osc52Sequence(s)
+	t.Setenv("STY", "1.pts-0")
+
+	var joined strings.Builder
+	for _, chunk := range strings.SplitAfter(osc52Sequence(s), "\x1b\\") {
+		if chunk == "" {
+			continue
+		}
+		if !strings.HasPrefix(chunk, "\x1bP") || !strings.HasSuffix(chunk, "\x1b\\") {
+			t.Fatalf("chunk %q isn't wrapped for screen", chunk)
+		}
+		chunk = strings.TrimSuffix(strings.TrimPrefix(chunk, "\x1bP"), "\x1b\\")
+		if len(chunk) > screenChunk {
+			t.Errorf("chunk of %d bytes, screen takes %d", len(chunk), screenChunk)
+		}
+		joined.WriteString(chunk)
+	}
+	if joined.String() != plain {
+		t.Errorf("the chunks make %q, want %q", joined.String(), plain)
+	}
+}
+
+func TestOSC52Write(t *testing.T) {
+	for _, name := range []string{"TMUX", "STY", "TERM"} {
+		t.Setenv(name, "")
+	}
+	var out bytes.Buffer
+	board := OSC52{Out: &out}
+	if err := board.Write("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "\x1b]52;c;aHVudGVyMg==\x07" {
+		t.Errorf("wrote %q", out.String())
+	}
+	if _, err := board.Read(); err != ErrUnreadable {
+		t.Errorf("Read = %v, want ErrUnreadable", err)
+	}
+}
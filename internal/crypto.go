@@ -0,0 +1,306 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrDecrypt is a cipher string that doesn't decrypt with the key at hand,
+// usually because the key is the wrong one.
+var ErrDecrypt = errors.New("couldn't decrypt")
+
+// Key derivation functions as numbered by Bitwarden.
+const (
+	KdfPBKDF2   = 0
+	KdfArgon2id = 1
+)
+
+// KdfConfig is how the master key is derived from the master password, as
+// the server tells it at prelogin.
+type KdfConfig struct {
+	Kdf        int `json:"kdf"`
+	Iterations int `json:"kdfIterations"`
+	// Memory is in MiB; it and Parallelism are only set for Argon2id
+	Memory      int `json:"kdfMemory"`
+	Parallelism int `json:"kdfParallelism"`
+}
+
+// masterKey derives the key that the vault's own key is encrypted with.
+// The email is the salt.
+func masterKey(password, email string, kdf KdfConfig) ([]byte, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if kdf.Iterations <= 0 {
+		return nil, errors.New("invalid KDF iterations")
+	}
+	switch kdf.Kdf {
+	case KdfPBKDF2:
+		return pbkdf2.Key([]byte(password), []byte(email), kdf.Iterations, 32, sha256.New), nil
+	case KdfArgon2id:
+		if kdf.Memory <= 0 || kdf.Parallelism <= 0 {
+			return nil, errors.New("invalid Argon2id parameters")
+		}
+		salt := sha256.Sum256([]byte(email))
+		return argon2.IDKey([]byte(password), salt[:], uint32(kdf.Iterations),
+			uint32(kdf.Memory)*1024, uint8(kdf.Parallelism), 32), nil
+	}
+	return nil, errors.New("unknown KDF " + strconv.Itoa(kdf.Kdf))
+}
+
+// masterPasswordHash is what the server checks the master password
+// against. The password itself never leaves the machine.
+func masterPasswordHash(masterKey []byte, password string) string {
+	hash := pbkdf2.Key(masterKey, []byte(password), 1, 32, sha256.New)
+	return base64.StdEncoding.EncodeToString(hash)
+}
+
+// symmetricKey is an AES-256 key along with the HMAC-SHA256 key that
+// authenticates what it encrypted. mac is nil for legacy keys.
+type symmetricKey struct {
+	enc, mac []byte
+}
+
+func newSymmetricKey(b []byte) (symmetricKey, error) {
+	switch len(b) {
+	case 32:
+		return symmetricKey{enc: b}, nil
+	case 64:
+		return symmetricKey{enc: b[:32], mac: b[32:]}, nil
+	}
+	return symmetricKey{}, errors.New("invalid key length " + strconv.Itoa(len(b)))
+}
+
+// stretch expands the 32 bytes of a master key into an encryption and a
+// MAC key.
+func stretch(masterKey []byte) (symmetricKey, error) {
+	key := symmetricKey{enc: make([]byte, 32), mac: make([]byte, 32)}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, masterKey, []byte("enc")), key.enc); err != nil {
+		return symmetricKey{}, err
+	}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, masterKey, []byte("mac")), key.mac); err != nil {
+		return symmetricKey{}, err
+	}
+	return key, nil
+}
+
+// Cipher string types as numbered by Bitwarden.
+const (
+	encAesCbc256           = 0
+	encAesCbc256HmacSha256 = 2
+	encRsaOaepSha256       = 3
+	encRsaOaepSha1         = 4
+	encRsaOaepSha1Hmac     = 6
+)
+
+// encString is a parsed cipher string, "2.iv|data|mac" for the usual type.
+type encString struct {
+	typ           int
+	iv, data, mac []byte
+}
+
+func parseEncString(s string) (encString, error) {
+	var e encString
+	dot := strings.Index(s, ".")
+	if dot < 0 {
+		return e, errors.New("invalid cipher string")
+	}
+	typ, err := strconv.Atoi(s[:dot])
+	if err != nil {
+		return e, errors.New("invalid cipher string")
+	}
+	e.typ = typ
+	var parts [][]byte
+	for _, part := range strings.Split(s[dot+1:], "|") {
+		b, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return e, errors.New("invalid cipher string")
+		}
+		parts = append(parts, b)
+	}
+	switch {
+	case typ == encAesCbc256 && len(parts) == 2:
+		e.iv, e.data = parts[0], parts[1]
+	case typ == encAesCbc256HmacSha256 && len(parts) == 3:
+		e.iv, e.data, e.mac = parts[0], parts[1], parts[2]
+	case (typ == encRsaOaepSha256 || typ == encRsaOaepSha1) && len(parts) == 1:
+		e.data = parts[0]
+	case typ == encRsaOaepSha1Hmac && len(parts) == 2:
+		// the MAC of RSA cipher strings has never been checked by anyone
+		e.data = parts[0]
+	default:
+		return e, errors.New("unsupported cipher string type " + strconv.Itoa(typ))
+	}
+	return e, nil
+}
+
+func (k symmetricKey) decrypt(s string) ([]byte, error) {
+	e, err := parseEncString(s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.typ {
+	case encAesCbc256HmacSha256:
+		if k.mac == nil || !hmac.Equal(k.sum(e.iv, e.data), e.mac) {
+			return nil, ErrDecrypt
+		}
+	case encAesCbc256:
+		if k.mac != nil {
+			return nil, ErrDecrypt
+		}
+	default:
+		return nil, ErrDecrypt
+	}
+	return k.decryptCBC(e.iv, e.data)
+}
+
+func (k symmetricKey) decryptString(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	b, err := k.decrypt(s)
+	return string(b), err
+}
+
+// decryptKey decrypts the key of an item, send or organization.
+func (k symmetricKey) decryptKey(s string) (symmetricKey, error) {
+	b, err := k.decrypt(s)
+	if err != nil {
+		return symmetricKey{}, err
+	}
+	return newSymmetricKey(b)
+}
+
+func (k symmetricKey) encrypt(plain []byte) (string, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(k.enc)
+	if err != nil {
+		return "", err
+	}
+	data := pad(plain)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	b64 := base64.StdEncoding.EncodeToString
+	if k.mac == nil {
+		return strconv.Itoa(encAesCbc256) + "." + b64(iv) + "|" + b64(data), nil
+	}
+	return strconv.Itoa(encAesCbc256HmacSha256) + "." + b64(iv) + "|" + b64(data) + "|" + b64(k.sum(iv, data)), nil
+}
+
+func (k symmetricKey) encryptString(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return k.encrypt([]byte(s))
+}
+
+// decryptFile decrypts an attachment, which is a cipher string in binary:
+// a type byte, the IV, the MAC, then the data.
+func (k symmetricKey) decryptFile(b []byte) ([]byte, error) {
+	if len(b) < 1+16+32 || b[0] != encAesCbc256HmacSha256 {
+		return nil, ErrDecrypt
+	}
+	iv, mac, data := b[1:17], b[17:49], b[49:]
+	if k.mac == nil || !hmac.Equal(k.sum(iv, data), mac) {
+		return nil, ErrDecrypt
+	}
+	return k.decryptCBC(iv, data)
+}
+
+func (k symmetricKey) sum(iv, data []byte) []byte {
+	h := hmac.New(sha256.New, k.mac)
+	h.Write(iv)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func (k symmetricKey) decryptCBC(iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(k.enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrDecrypt
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	return unpad(plain)
+}
+
+func pad(b []byte) []byte {
+	n := aes.BlockSize - len(b)%aes.BlockSize
+	return append(append([]byte(nil), b...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func unpad(b []byte) ([]byte, error) {
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, ErrDecrypt
+	}
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, ErrDecrypt
+		}
+	}
+	return b[:len(b)-n], nil
+}
+
+// parsePrivateKey reads the user's RSA key, which organization keys are
+// encrypted with.
+func parsePrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func decryptRSA(key *rsa.PrivateKey, s string) (symmetricKey, error) {
+	e, err := parseEncString(s)
+	if err != nil {
+		return symmetricKey{}, err
+	}
+	var plain []byte
+	switch e.typ {
+	case encRsaOaepSha256:
+		plain, err = rsa.DecryptOAEP(sha256.New(), nil, key, e.data, nil)
+	case encRsaOaepSha1, encRsaOaepSha1Hmac:
+		plain, err = rsa.DecryptOAEP(sha1.New(), nil, key, e.data, nil)
+	default:
+		return symmetricKey{}, ErrDecrypt
+	}
+	if err != nil {
+		return symmetricKey{}, ErrDecrypt
+	}
+	return newSymmetricKey(plain)
+}
+
+// sendKey derives the key of a send from the random material its link
+// carries.
+func sendKey(material []byte) (symmetricKey, error) {
+	b := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, material, []byte("bitwarden-send"), []byte("send")), b); err != nil {
+		return symmetricKey{}, err
+	}
+	return newSymmetricKey(b)
+}
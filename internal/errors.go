@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// CommandError is a bw command that failed. Err is the reason, as far as
+// the exit code and stderr tell it, and matches the Err values above with
+// errors.Is. Stderr keeps the whole story for a closer look.
+type CommandError struct {
+	// Command is the bw subcommand, like "list items"
+	Command  string
+	ExitCode int
+	// Stderr is what bw printed, with secrets blanked out
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string { return e.Err.Error() }
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// commandError wraps the failure of bw args, redacting secrets from its
+// stderr. A command killed because ctx ended fails with ctx's error.
+func commandError(ctx context.Context, args []string, err error, secrets []string) error {
+	if len(args) > 2 {
+		// the rest are ids and encoded payloads
+		args = args[:2]
+	}
+	cmdErr := &CommandError{Command: strings.Join(args, " "), Err: err}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		cmdErr.Err = ErrCLIMissing
+	case errors.As(err, &exitErr):
+		cmdErr.ExitCode = exitErr.ExitCode()
+		cmdErr.Stderr = redact(strings.TrimSpace(string(exitErr.Stderr)), secrets)
+		cmdErr.Err = classify(cmdErr.Stderr, cmdErr.ExitCode)
+	}
+	if ctx.Err() != nil {
+		cmdErr.Err = ctx.Err()
+	}
+	return cmdErr
+}
+
+// networkErrors are what node prints when bw can't reach the server.
+var networkErrors = []string{
+	"ECONNREFUSED", "ECONNRESET", "ENOTFOUND", "ETIMEDOUT", "EAI_AGAIN",
+	"ENETUNREACH", "getaddrinfo", "fetch failed", "socket hang up",
+}
+
+// classify tells from bw's stderr why a command failed. Failures it
+// doesn't know come out as the first line bw printed.
+func classify(stderr string, exitCode int) error {
+	switch {
+	case strings.Contains(stderr, "You are not logged in"):
+		return ErrNotLoggedIn
+	case strings.Contains(stderr, "Vault is locked"):
+		return ErrLocked
+	case strings.Contains(stderr, "Invalid master password"):
+		return ErrInvalidPassword
+	case strings.Contains(stderr, "Not found"):
+		return ErrNotFound
+	}
+	for _, s := range networkErrors {
+		if strings.Contains(stderr, s) {
+			return ErrNetwork
+		}
+	}
+	if line := strings.SplitN(stderr, "\n", 2)[0]; line != "" {
+		return errors.New(strings.TrimSpace(line))
+	}
+	return fmt.Errorf("bw exited with status %d", exitCode)
+}
+
+// tokenPattern matches session keys and bw encode payloads, which may hold
+// secrets of their own.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{40,}={0,2}`)
+
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[redacted]")
+		}
+	}
+	return tokenPattern.ReplaceAllString(s, "[redacted]")
+}
+
+// decodeOutput parses the JSON a bw command printed.
+func decodeOutput(output []byte, v interface{}) error {
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnexpectedOutput, err)
+	}
+	return nil
+}
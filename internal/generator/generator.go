@@ -0,0 +1,248 @@
+// Package generator makes passwords and passphrases the way Bitwarden's
+// own generator does, using crypto/rand and the EFF large wordlist.
+package generator
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+	special   = "!@#$%^&*"
+	// ambiguous characters are easily mistaken for one another
+	ambiguous = "IlO01"
+)
+
+var (
+	ErrNoCharacters = errors.New("select at least one character class")
+	ErrTooShort     = errors.New("length is too short for the minimums")
+	ErrNoWords      = errors.New("a passphrase needs at least one word")
+)
+
+//go:embed eff_large_wordlist.txt
+var wordlistData string
+
+// Wordlist is the EFF large wordlist, 7776 words meant for passphrases.
+var Wordlist = parseWordlist(wordlistData)
+
+func parseWordlist(data string) []string {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			words = append(words, fields[1])
+		}
+	}
+	return words
+}
+
+type PasswordOptions struct {
+	Length         int
+	Uppercase      bool
+	Lowercase      bool
+	Digits         bool
+	Special        bool
+	AvoidAmbiguous bool
+	MinDigits      int
+	MinSpecial     int
+}
+
+type PassphraseOptions struct {
+	Words         int
+	Separator     string
+	Capitalize    bool
+	IncludeNumber bool
+}
+
+func DefaultPasswordOptions() PasswordOptions {
+	return PasswordOptions{
+		Length:     16,
+		Uppercase:  true,
+		Lowercase:  true,
+		Digits:     true,
+		MinDigits:  1,
+		MinSpecial: 0,
+	}
+}
+
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{
+		Words:     4,
+		Separator: "-",
+	}
+}
+
+func (o PasswordOptions) charset(set string) string {
+	if !o.AvoidAmbiguous {
+		return set
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguous, r) {
+			return -1
+		}
+		return r
+	}, set)
+}
+
+// pool is every character the password may contain.
+func (o PasswordOptions) pool() string {
+	var b strings.Builder
+	if o.Uppercase {
+		b.WriteString(o.charset(uppercase))
+	}
+	if o.Lowercase {
+		b.WriteString(o.charset(lowercase))
+	}
+	if o.Digits {
+		b.WriteString(o.charset(digits))
+	}
+	if o.Special {
+		b.WriteString(special)
+	}
+	return b.String()
+}
+
+// Password generates a password with at least one character of every
+// selected class and at least the minimum count of digits and specials.
+func Password(o PasswordOptions) (string, error) {
+	pool := o.pool()
+	if pool == "" {
+		return "", ErrNoCharacters
+	}
+	var required []string
+	if o.Uppercase {
+		required = append(required, o.charset(uppercase))
+	}
+	if o.Lowercase {
+		required = append(required, o.charset(lowercase))
+	}
+	if o.Digits {
+		for i := 0; i < max(o.MinDigits, 1); i++ {
+			required = append(required, o.charset(digits))
+		}
+	}
+	if o.Special {
+		for i := 0; i < max(o.MinSpecial, 1); i++ {
+			required = append(required, special)
+		}
+	}
+	if len(required) > o.Length {
+		return "", ErrTooShort
+	}
+	for len(required) < o.Length {
+		required = append(required, pool)
+	}
+
+	password := make([]byte, len(required))
+	for i, set := range required {
+		c, err := pick(set)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+	if err := shuffle(password); err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// Passphrase generates words from the wordlist joined by the separator,
+// with a digit appended to one random word if asked for.
+func Passphrase(o PassphraseOptions) (string, error) {
+	if o.Words < 1 {
+		return "", ErrNoWords
+	}
+	words := make([]string, o.Words)
+	for i := range words {
+		n, err := randInt(len(Wordlist))
+		if err != nil {
+			return "", err
+		}
+		word := Wordlist[n]
+		if o.Capitalize {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	if o.IncludeNumber {
+		i, err := randInt(len(words))
+		if err != nil {
+			return "", err
+		}
+		n, err := randInt(10)
+		if err != nil {
+			return "", err
+		}
+		words[i] += strconv.Itoa(n)
+	}
+	return strings.Join(words, o.Separator), nil
+}
+
+// PasswordEntropy estimates the bits of entropy of passwords made with o,
+// as if every character were drawn from the whole pool.
+func PasswordEntropy(o PasswordOptions) float64 {
+	pool := len(o.pool())
+	if pool == 0 {
+		return 0
+	}
+	return float64(o.Length) * math.Log2(float64(pool))
+}
+
+// PassphraseEntropy estimates the bits of entropy of passphrases made with
+// o. The separator and capitalization add nothing.
+func PassphraseEntropy(o PassphraseOptions) float64 {
+	if o.Words < 1 {
+		return 0
+	}
+	bits := float64(o.Words) * math.Log2(float64(len(Wordlist)))
+	if o.IncludeNumber {
+		bits += math.Log2(10) + math.Log2(float64(o.Words))
+	}
+	return bits
+}
+
+func pick(set string) (byte, error) {
+	i, err := randInt(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[i], nil
+}
+
+// shuffle is a Fisher-Yates shuffle driven by crypto/rand.
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
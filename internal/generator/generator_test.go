@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// runs is how many passwords each test draws, enough for a rule that
+// only sometimes holds to show.
+const runs = 200
+
+func count(s, set string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(set, r) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPasswordMinimums(t *testing.T) {
+	o := PasswordOptions{
+		Length:     12,
+		Lowercase:  true,
+		Digits:     true,
+		Special:    true,
+		MinDigits:  4,
+		MinSpecial: 3,
+	}
+	for i := 0; i < runs; i++ {
+		password, err := Password(o)
+		if err != nil {
+			t.Fatalf("Password: %v", err)
+		}
+		if len(password) != o.Length {
+			t.Fatalf("%q is %d long, want %d", password, len(password), o.Length)
+		}
+		if count(password, digits) < o.MinDigits || count(password, special) < o.MinSpecial {
+			t.Fatalf("%q has too few digits or specials", password)
+		}
+		if count(password, lowercase) < 1 {
+			t.Fatalf("%q has no lowercase letter", password)
+		}
+		if count(password, uppercase) > 0 {
+			t.Fatalf("%q has uppercase letters, which are off", password)
+		}
+	}
+}
+
+func TestPasswordTooShort(t *testing.T) {
+	o := PasswordOptions{Length: 5, Lowercase: true, Digits: true, MinDigits: 5}
+	if _, err := Password(o); !errors.Is(err, ErrTooShort) {
+		t.Errorf("Password = %v, want ErrTooShort", err)
+	}
+}
+
+func TestPasswordNoCharacters(t *testing.T) {
+	o := PasswordOptions{Length: 16, MinDigits: 1}
+	if _, err := Password(o); !errors.Is(err, ErrNoCharacters) {
+		t.Errorf("Password = %v, want ErrNoCharacters", err)
+	}
+	if PasswordEntropy(o) != 0 {
+		t.Errorf("entropy = %v without characters, want 0", PasswordEntropy(o))
+	}
+}
+
+func TestPasswordAvoidAmbiguous(t *testing.T) {
+	o := DefaultPasswordOptions()
+	o.Length = 64
+	o.AvoidAmbiguous = true
+	for i := 0; i < runs; i++ {
+		password, err := Password(o)
+		if err != nil {
+			t.Fatalf("Password: %v", err)
+		}
+		if strings.ContainsAny(password, ambiguous) {
+			t.Fatalf("%q has ambiguous characters", password)
+		}
+	}
+	// 26+26+10 characters, less I, l, O, 0 and 1
+	if got, want := len(o.pool()), 57; got != want {
+		t.Errorf("pool has %d characters, want %d", got, want)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	if len(Wordlist) != 7776 {
+		t.Fatalf("wordlist has %d words, want 7776", len(Wordlist))
+	}
+	known := make(map[string]bool, len(Wordlist))
+	for _, word := range Wordlist {
+		known[word] = true
+	}
+	o := PassphraseOptions{Words: 6, Separator: "_", Capitalize: true, IncludeNumber: true}
+	for i := 0; i < runs; i++ {
+		passphrase, err := Passphrase(o)
+		if err != nil {
+			t.Fatalf("Passphrase: %v", err)
+		}
+		words := strings.Split(passphrase, o.Separator)
+		if len(words) != o.Words {
+			t.Fatalf("%q has %d words, want %d", passphrase, len(words), o.Words)
+		}
+		numbered := 0
+		for _, word := range words {
+			if !unicode.IsUpper(rune(word[0])) {
+				t.Fatalf("%q isn't capitalized", word)
+			}
+			if trimmed := strings.TrimRight(word, digits); trimmed != word {
+				numbered++
+				word = trimmed
+			}
+			if !known[strings.ToLower(word)] {
+				t.Fatalf("%q isn't from the wordlist", word)
+			}
+		}
+		if numbered != 1 {
+			t.Fatalf("%q has a number on %d words, want 1", passphrase, numbered)
+		}
+	}
+}
+
+func TestPassphraseNoWords(t *testing.T) {
+	if _, err := Passphrase(PassphraseOptions{Separator: "-"}); !errors.Is(err, ErrNoWords) {
+		t.Errorf("Passphrase = %v, want ErrNoWords", err)
+	}
+}
@@ -0,0 +1,254 @@
+package backend
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// extraFields holds the JSON members of a Bitwarden object that bwtui does
+// not model. They are written back unchanged so that editing an item never
+// drops data the CLI knows about and we don't.
+type extraFields map[string]json.RawMessage
+
+// decodeExtra decodes data into v, a pointer to a struct, and returns every
+// member that didn't map onto one of v's fields.
+func decodeExtra(data []byte, v interface{}) (extraFields, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var all extraFields
+	if err := json.Unmarshal(data, &all); err != nil {
+		// null and other non-objects carry nothing extra
+		return nil, nil
+	}
+	for _, name := range jsonNames(reflect.TypeOf(v).Elem()) {
+		delete(all, name)
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// encodeExtra encodes v and merges extra back into the resulting object.
+// Modelled fields win over extra members of the same name.
+func encodeExtra(v interface{}, extra extraFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var all extraFields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for k, raw := range extra {
+		if _, ok := all[k]; !ok {
+			all[k] = raw
+		}
+	}
+	return json.Marshal(all)
+}
+
+func jsonNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (i *Item) UnmarshalJSON(data []byte) error {
+	type plain Item
+	extra, err := decodeExtra(data, (*plain)(i))
+	i.extra = extra
+	return err
+}
+
+// typeKeys maps the type-specific members of an item to their item type.
+var typeKeys = map[string]int{
+	"login":      TypeLogin,
+	"secureNote": TypeSecureNote,
+	"card":       TypeCard,
+	"identity":   TypeIdentity,
+}
+
+func (i Item) MarshalJSON() ([]byte, error) {
+	type plain Item
+	data, err := encodeExtra(plain(i), i.extra)
+	if err != nil {
+		return nil, err
+	}
+	var all extraFields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	// only the member matching the item's type is meaningful
+	for k, t := range typeKeys {
+		if t != i.Type {
+			delete(all, k)
+		}
+	}
+	// bw wants null, not an empty string, for items outside any folder
+	if i.FolderId == "" {
+		all["folderId"] = json.RawMessage("null")
+	}
+	return json.Marshal(all)
+}
+
+func (l *Login) UnmarshalJSON(data []byte) error {
+	type plain Login
+	extra, err := decodeExtra(data, (*plain)(l))
+	l.extra = extra
+	return err
+}
+
+func (l Login) MarshalJSON() ([]byte, error) {
+	type plain Login
+	return encodeExtra(plain(l), l.extra)
+}
+
+func (u *Uri) UnmarshalJSON(data []byte) error {
+	type plain Uri
+	extra, err := decodeExtra(data, (*plain)(u))
+	u.extra = extra
+	return err
+}
+
+func (u Uri) MarshalJSON() ([]byte, error) {
+	type plain Uri
+	return encodeExtra(plain(u), u.extra)
+}
+
+func (f *Field) UnmarshalJSON(data []byte) error {
+	type plain Field
+	extra, err := decodeExtra(data, (*plain)(f))
+	f.extra = extra
+	return err
+}
+
+func (f Field) MarshalJSON() ([]byte, error) {
+	type plain Field
+	return encodeExtra(plain(f), f.extra)
+}
+
+func (c *Card) UnmarshalJSON(data []byte) error {
+	type plain Card
+	extra, err := decodeExtra(data, (*plain)(c))
+	c.extra = extra
+	return err
+}
+
+func (c Card) MarshalJSON() ([]byte, error) {
+	type plain Card
+	return encodeExtra(plain(c), c.extra)
+}
+
+func (i *Identity) UnmarshalJSON(data []byte) error {
+	type plain Identity
+	extra, err := decodeExtra(data, (*plain)(i))
+	i.extra = extra
+	return err
+}
+
+func (i Identity) MarshalJSON() ([]byte, error) {
+	type plain Identity
+	return encodeExtra(plain(i), i.extra)
+}
+
+func (n *SecureNote) UnmarshalJSON(data []byte) error {
+	type plain SecureNote
+	extra, err := decodeExtra(data, (*plain)(n))
+	n.extra = extra
+	return err
+}
+
+func (n SecureNote) MarshalJSON() ([]byte, error) {
+	type plain SecureNote
+	return encodeExtra(plain(n), n.extra)
+}
+
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	type plain Attachment
+	extra, err := decodeExtra(data, (*plain)(a))
+	a.extra = extra
+	return err
+}
+
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	type plain Attachment
+	return encodeExtra(plain(a), a.extra)
+}
+
+func (s *Send) UnmarshalJSON(data []byte) error {
+	type plain Send
+	extra, err := decodeExtra(data, (*plain)(s))
+	s.extra = extra
+	return err
+}
+
+func (s Send) MarshalJSON() ([]byte, error) {
+	type plain Send
+	data, err := encodeExtra(plain(s), s.extra)
+	if err != nil {
+		return nil, err
+	}
+	var all extraFields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	// like items, a send only keeps the member of its own type
+	if s.Type == SendTypeFile {
+		delete(all, "text")
+	} else {
+		delete(all, "file")
+	}
+	return json.Marshal(all)
+}
+
+func (t *SendText) UnmarshalJSON(data []byte) error {
+	type plain SendText
+	extra, err := decodeExtra(data, (*plain)(t))
+	t.extra = extra
+	return err
+}
+
+func (t SendText) MarshalJSON() ([]byte, error) {
+	type plain SendText
+	return encodeExtra(plain(t), t.extra)
+}
+
+func (f *SendFile) UnmarshalJSON(data []byte) error {
+	type plain SendFile
+	extra, err := decodeExtra(data, (*plain)(f))
+	f.extra = extra
+	return err
+}
+
+func (f SendFile) MarshalJSON() ([]byte, error) {
+	type plain SendFile
+	return encodeExtra(plain(f), f.extra)
+}
+
+func (h *PasswordHistory) UnmarshalJSON(data []byte) error {
+	type plain PasswordHistory
+	extra, err := decodeExtra(data, (*plain)(h))
+	h.extra = extra
+	return err
+}
+
+func (h PasswordHistory) MarshalJSON() ([]byte, error) {
+	type plain PasswordHistory
+	return encodeExtra(plain(h), h.extra)
+}
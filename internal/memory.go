@@ -0,0 +1,426 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Memory is an in-memory Vault. It never touches the bw CLI, which makes it
+// suitable for driving the TUI in tests.
+type Memory struct {
+	Email    string
+	Password string
+	Items    []Item
+	Folders  []Folder
+	Sends    []Send
+	// LoggedOut makes the vault start unauthenticated
+	LoggedOut bool
+	// TwoFactorCode, when set, is required to log in
+	TwoFactorCode string
+	// Files holds the contents of attachments by attachment id
+	Files map[string][]byte
+	// Synced is when Sync was last called
+	Synced *time.Time
+	Server ServerConfig
+
+	unlocked bool
+}
+
+func NewMemory(password string, items []Item, folders []Folder) *Memory {
+	return &Memory{
+		Password: password,
+		Items:    items,
+		Folders:  folders,
+	}
+}
+
+func (m *Memory) Status(ctx context.Context) (*Status, error) {
+	status := &Status{ServerUrl: m.Server.Url, UserEmail: m.Email, Status: StatusLocked}
+	if m.LoggedOut {
+		status.Status = StatusUnauthenticated
+	} else if m.unlocked {
+		status.Status = StatusUnlocked
+	}
+	return status, nil
+}
+
+func (m *Memory) Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error {
+	if email != m.Email || password != m.Password {
+		return ErrInvalidPassword
+	}
+	if m.TwoFactorCode != "" {
+		if twoFactor == nil || twoFactor.Code == "" {
+			return ErrTwoFactorRequired
+		}
+		if twoFactor.Code != m.TwoFactorCode {
+			return ErrInvalidTwoFactor
+		}
+	}
+	m.LoggedOut = false
+	m.unlocked = true
+	return nil
+}
+
+func (m *Memory) LoginAPIKey(ctx context.Context, clientId, clientSecret string) error {
+	m.LoggedOut = false
+	return nil
+}
+
+func (m *Memory) Unlock(ctx context.Context, password string) error {
+	if m.LoggedOut {
+		return ErrLocked
+	}
+	if password != m.Password {
+		return ErrInvalidPassword
+	}
+	m.unlocked = true
+	return nil
+}
+
+func (m *Memory) Lock(ctx context.Context) error {
+	m.unlocked = false
+	return nil
+}
+
+func (m *Memory) GetItems(ctx context.Context, filter FilterOptions) ([]Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	items := Filter(m.Items, filter.matches)
+	return items, nil
+}
+
+func (m *Memory) GetItem(ctx context.Context, id string) (*Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	for _, i := range m.Items {
+		if i.Id == id {
+			item := i
+			return &item, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) GetFolder(ctx context.Context, id string) (*Folder, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	for _, f := range m.Folders {
+		if f.Id == id {
+			folder := f
+			return &folder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) GetFolders(ctx context.Context) ([]Folder, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	return append([]Folder(nil), m.Folders...), nil
+}
+
+func (m *Memory) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	folder := Folder{Id: newId(), Name: name}
+	m.Folders = append(m.Folders, folder)
+	return &folder, nil
+}
+
+func (m *Memory) EditFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	for i := range m.Folders {
+		if m.Folders[i].Id == folder.Id {
+			m.Folders[i] = folder
+			return &folder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteFolder removes a folder; its items move out of any folder, as they
+// do in Bitwarden.
+func (m *Memory) DeleteFolder(ctx context.Context, id string) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	for i := range m.Folders {
+		if m.Folders[i].Id != id {
+			continue
+		}
+		m.Folders = append(m.Folders[:i], m.Folders[i+1:]...)
+		for j := range m.Items {
+			if m.Items[j].FolderId == id {
+				m.Items[j].FolderId = ""
+			}
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) CreateItem(ctx context.Context, item Item) (*Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	item.Id = newId()
+	m.Items = append(m.Items, item)
+	return &item, nil
+}
+
+func (m *Memory) EditItem(ctx context.Context, item Item) (*Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	for i := range m.Items {
+		if m.Items[i].Id == item.Id {
+			m.Items[i] = item
+			return &item, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	for i := range m.Items {
+		if m.Items[i].Id != id {
+			continue
+		}
+		if permanent {
+			m.Items = append(m.Items[:i], m.Items[i+1:]...)
+		} else {
+			now := time.Now()
+			m.Items[i].DeletedDate = &now
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) RestoreItem(ctx context.Context, id string) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	for i := range m.Items {
+		if m.Items[i].Id == id && m.Items[i].DeletedDate != nil {
+			m.Items[i].DeletedDate = nil
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) GetTrash(ctx context.Context) ([]Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	return Filter(m.Items, func(i Item) bool {
+		return i.DeletedDate != nil
+	}), nil
+}
+
+func (m *Memory) DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	data, ok := m.Files[attachmentId]
+	if !ok {
+		return ErrNotFound
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (m *Memory) UploadAttachment(ctx context.Context, itemId, path string) (*Item, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	item := m.item(itemId)
+	if item == nil {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	attachment := Attachment{
+		Id:       newId(),
+		FileName: filepath.Base(path),
+		Size:     strconv.Itoa(len(data)),
+		SizeName: strconv.Itoa(len(data)) + " Bytes",
+	}
+	if m.Files == nil {
+		m.Files = map[string][]byte{}
+	}
+	m.Files[attachment.Id] = data
+	item.Attachments = append(item.Attachments, attachment)
+	updated := *item
+	return &updated, nil
+}
+
+func (m *Memory) DeleteAttachment(ctx context.Context, itemId, attachmentId string) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	item := m.item(itemId)
+	if item == nil {
+		return ErrNotFound
+	}
+	for i, a := range item.Attachments {
+		if a.Id == attachmentId {
+			item.Attachments = append(item.Attachments[:i:i], item.Attachments[i+1:]...)
+			delete(m.Files, attachmentId)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) Sync(ctx context.Context) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	now := time.Now()
+	m.Synced = &now
+	return nil
+}
+
+func (m *Memory) LastSync(ctx context.Context) (*time.Time, error) {
+	return m.Synced, nil
+}
+
+func (m *Memory) GetServer(ctx context.Context) (*ServerConfig, error) {
+	server := m.Server
+	return &server, nil
+}
+
+func (m *Memory) SetServer(ctx context.Context, server ServerConfig) error {
+	m.Server = server
+	m.LoggedOut = true
+	m.unlocked = false
+	return nil
+}
+
+func (m *Memory) GetSends(ctx context.Context) ([]Send, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	return append([]Send(nil), m.Sends...), nil
+}
+
+func (m *Memory) CreateSend(ctx context.Context, send Send) (*Send, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	if send.Type == SendTypeFile {
+		data, err := os.ReadFile(send.File.FileName)
+		if err != nil {
+			return nil, err
+		}
+		send.File = SendFile{
+			Id:       newId(),
+			FileName: filepath.Base(send.File.FileName),
+			Size:     strconv.Itoa(len(data)),
+			SizeName: strconv.Itoa(len(data)) + " Bytes",
+		}
+	}
+	send.Id = newId()
+	send.AccessId = newId()
+	send.AccessUrl = "https://vault.bitwarden.com/#/send/" + send.AccessId
+	send.PasswordSet = send.Password != ""
+	send.Password = ""
+	if send.DeletionDate == nil {
+		deletion := time.Now().AddDate(0, 0, 7)
+		send.DeletionDate = &deletion
+	}
+	m.Sends = append(m.Sends, send)
+	return &send, nil
+}
+
+func (m *Memory) EditSend(ctx context.Context, send Send) (*Send, error) {
+	if !m.unlocked {
+		return nil, ErrLocked
+	}
+	for i := range m.Sends {
+		if m.Sends[i].Id == send.Id {
+			send.PasswordSet = send.PasswordSet || send.Password != ""
+			send.Password = ""
+			m.Sends[i] = send
+			return &send, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) DeleteSend(ctx context.Context, id string) error {
+	if !m.unlocked {
+		return ErrLocked
+	}
+	for i := range m.Sends {
+		if m.Sends[i].Id == id {
+			m.Sends = append(m.Sends[:i], m.Sends[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) item(id string) *Item {
+	for i := range m.Items {
+		if m.Items[i].Id == id {
+			return &m.Items[i]
+		}
+	}
+	return nil
+}
+
+// matches tells whether an item outside the trash passes the filter, the
+// way bw list items filters.
+func (f FilterOptions) matches(i Item) bool {
+	if i.DeletedDate != nil {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(i.Name), strings.ToLower(f.Search)) {
+		return false
+	}
+	if f.Url != "" && !hasUri(i, f.Url) {
+		return false
+	}
+	if f.FolderId == "null" && i.FolderId != "" {
+		return false
+	}
+	if f.FolderId != "" && f.FolderId != "null" && i.FolderId != f.FolderId {
+		return false
+	}
+	return true
+}
+
+func hasUri(i Item, url string) bool {
+	for _, u := range i.Login.Uris {
+		if strings.Contains(u.Uri, url) {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Send types as numbered by Bitwarden.
+const (
+	SendTypeText = 0
+	SendTypeFile = 1
+)
+
+// Send shares a text or a file through a link, with limits on how long and
+// how often it can be opened.
+type Send struct {
+	Id        string   `json:"id"`
+	AccessId  string   `json:"accessId"`
+	AccessUrl string   `json:"accessUrl"`
+	Name      string   `json:"name"`
+	Notes     string   `json:"notes"`
+	Type      int      `json:"type"`
+	Text      SendText `json:"text"`
+	File      SendFile `json:"file"`
+	// MaxAccessCount is nil for sends that can be opened any number of times
+	MaxAccessCount *int       `json:"maxAccessCount"`
+	AccessCount    int        `json:"accessCount"`
+	DeletionDate   *time.Time `json:"deletionDate,omitempty"`
+	ExpirationDate *time.Time `json:"expirationDate"`
+	// Password sets a new password. PasswordSet tells whether there is one,
+	// bw never returns it.
+	Password    string `json:"password,omitempty"`
+	PasswordSet bool   `json:"passwordSet"`
+	Disabled    bool   `json:"disabled"`
+	HideEmail   bool   `json:"hideEmail"`
+
+	extra extraFields
+}
+
+type SendText struct {
+	Text string `json:"text"`
+	// Hidden makes the recipient click to reveal the text
+	Hidden bool `json:"hidden"`
+
+	extra extraFields
+}
+
+type SendFile struct {
+	Id string `json:"id"`
+	// FileName is the path of the file to send when creating one
+	FileName string `json:"fileName"`
+	Size     string `json:"size"`
+	SizeName string `json:"sizeName"`
+
+	extra extraFields
+}
+
+func (c *Context) GetSends(ctx context.Context) ([]Send, error) {
+	output, err := c.exec(ctx, "send", "list")
+	if err != nil {
+		return nil, err
+	}
+	var sends []Send
+	err = decodeOutput(output, &sends)
+	if err != nil {
+		return nil, err
+	}
+	return sends, nil
+}
+
+// CreateSend fills bw's send template of the send's type and creates it.
+func (c *Context) CreateSend(ctx context.Context, send Send) (*Send, error) {
+	template := "send.text"
+	if send.Type == SendTypeFile {
+		template = "send.file"
+	}
+	output, err := c.exec(ctx, "send", "template", template)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := templatePayload(output, send)
+	if err != nil {
+		return nil, err
+	}
+	return c.saveSend(ctx, payload, "create")
+}
+
+func (c *Context) EditSend(ctx context.Context, send Send) (*Send, error) {
+	payload, err := json.Marshal(send)
+	if err != nil {
+		return nil, err
+	}
+	return c.saveSend(ctx, payload, "edit")
+}
+
+func (c *Context) saveSend(ctx context.Context, payload []byte, command string) (*Send, error) {
+	encoded, err := c.encode(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	// piped in, as the text and password would show in ps as an argument
+	output, err := c.run(ctx, nil, []byte(encoded), "send", command)
+	if err != nil {
+		return nil, err
+	}
+	var saved *Send
+	err = decodeOutput(output, &saved)
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (c *Context) DeleteSend(ctx context.Context, id string) error {
+	_, err := c.exec(ctx, "send", "delete", id)
+	return err
+}
@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSendSecretsStayOutOfArgv(t *testing.T) {
+	dir := fakeBW(t, `{"id": "s1", "type": 0, "name": "Wifi", "text": {"text": "hunter2"}}`)
+	c := &Context{Binary: "bw", SessionKey: "SESSIONKEY"}
+	send := Send{Id: "s1", Name: "Wifi", Text: SendText{Text: "hunter2"}, Password: "opensesame"}
+	if _, err := c.EditSend(context.Background(), send); err != nil {
+		t.Fatalf("EditSend: %v", err)
+	}
+	argv := readRecorded(t, dir, "argv")
+	for _, secret := range []string{"hunter2", "opensesame", "SESSIONKEY"} {
+		if strings.Contains(argv, secret) {
+			t.Errorf("%s in argv %q", secret, argv)
+		}
+	}
+	// the encoded send went in on stdin
+	if stdin := readRecorded(t, dir, "stdin"); !strings.Contains(stdin, "hunter2") {
+		t.Errorf("stdin = %q, want the send", stdin)
+	}
+}
@@ -0,0 +1,629 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultServeAddr is where bw serve listens unless told otherwise: a Unix
+// socket in bwtui/ of the user's runtime directory, or of their cache
+// directory without one. It's "" when the system has neither.
+func DefaultServeAddr() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserCacheDir(); err != nil {
+			return ""
+		}
+	}
+	return "unix:" + filepath.Join(dir, "bwtui", "serve.sock")
+}
+
+// Serve is a Vault that talks to bw serve, the REST API the bw CLI offers
+// on localhost. A single bw process stays up and keeps the vault unlocked,
+// sparing every call the start-up of a new one. Whatever can reach Addr
+// can read the vault while it is unlocked; a Unix socket in a private
+// directory keeps it to the user.
+type Serve struct {
+	// Addr is where bw serve listens: host:port, or unix:path for a Unix
+	// socket.
+	Addr string
+	// Binary is the bw executable started when nothing serves Addr yet.
+	// Nothing is started when it is empty, Addr must be served already.
+	Binary string
+	// AppDataDir and SessionKey are the data directory and session of the
+	// bw started. bw picks the directory when AppDataDir is empty.
+	AppDataDir string
+	SessionKey string
+	// Client talks to Addr. NewServe sets up one that dials the socket of
+	// a unix: Addr.
+	Client *http.Client
+
+	mu sync.Mutex
+	// ready is set once Addr answered
+	ready bool
+	// cmd is the bw serve that was started, exited is closed when it ends
+	cmd    *exec.Cmd
+	exited chan struct{}
+	stderr bytes.Buffer
+}
+
+// serveResponse is how bw serve wraps every answer.
+type serveResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+var _ Vault = (*Serve)(nil)
+
+// NewServe talks to bw serve at addr, starting it if nothing serves addr.
+func NewServe(addr string) *Serve {
+	s := &Serve{Addr: addr, Binary: "bw", SessionKey: os.Getenv("BW_SESSION")}
+	if path, ok := s.socket(); ok {
+		s.Client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}}
+	}
+	return s
+}
+
+// OverTCP tells whether Addr is a TCP port, which every user of the
+// machine can connect to.
+func (s *Serve) OverTCP() bool {
+	_, ok := s.socket()
+	return !ok
+}
+
+// socket is the path of the Unix socket Addr names, if it names one.
+func (s *Serve) socket() (string, bool) {
+	if !strings.HasPrefix(s.Addr, "unix:") {
+		return "", false
+	}
+	return strings.TrimPrefix(s.Addr, "unix:"), true
+}
+
+func (s *Serve) baseUrl() string {
+	if _, ok := s.socket(); ok {
+		// the host goes unused, the client dials the socket
+		return "http://localhost"
+	}
+	return "http://" + s.Addr
+}
+
+func (s *Serve) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+// start makes sure something serves Addr, starting bw serve and waiting
+// for it to answer when nothing does.
+func (s *Serve) start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready && !s.ended() {
+		return nil
+	}
+	s.ready = false
+	if s.ping(ctx) {
+		s.ready = true
+		return nil
+	}
+	if s.Binary == "" {
+		return fmt.Errorf("%w: nothing serves %s", ErrNetwork, s.Addr)
+	}
+	if s.cmd == nil || s.ended() {
+		if err := s.run(); err != nil {
+			return err
+		}
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// bw carries on starting, the next call waits for it again
+			return ctx.Err()
+		case <-s.exited:
+			return s.exitError()
+		case <-ticker.C:
+			if s.ping(ctx) {
+				s.ready = true
+				return nil
+			}
+		}
+	}
+}
+
+// run starts bw serve on Addr. It outlives the call that started it and
+// ends with Close.
+func (s *Serve) run() error {
+	args := []string{"serve"}
+	if path, ok := s.socket(); ok {
+		// only the user may enter the directory, and so reach the socket
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		// a socket nothing listens on is left over from a bw that ended
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+		} else if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		args = append(args, "--hostname", "unix:"+path)
+	} else {
+		host, port, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return fmt.Errorf("bad bw serve address %s: %w", s.Addr, err)
+		}
+		args = append(args, "--hostname", host, "--port", port)
+	}
+	cmd := exec.Command(s.Binary, args...)
+	cmd.Env = append(os.Environ(), "BW_SESSION="+s.SessionKey)
+	if s.AppDataDir != "" {
+		cmd.Env = append(cmd.Env, "BITWARDENCLI_APPDATA_DIR="+s.AppDataDir)
+	}
+	s.stderr.Reset()
+	cmd.Stderr = &s.stderr
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return &CommandError{Command: "serve", Err: ErrCLIMissing}
+		}
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	s.cmd, s.exited = cmd, exited
+	return nil
+}
+
+// ended tells whether the bw serve that was started has ended.
+func (s *Serve) ended() bool {
+	if s.exited == nil {
+		return false
+	}
+	select {
+	case <-s.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// exitError is why the bw serve that was started ended.
+func (s *Serve) exitError() error {
+	cmdErr := &CommandError{
+		Command:  "serve",
+		ExitCode: s.cmd.ProcessState.ExitCode(),
+		Stderr:   redact(strings.TrimSpace(s.stderr.String()), []string{s.SessionKey}),
+	}
+	cmdErr.Err = classify(cmdErr.Stderr, cmdErr.ExitCode)
+	return cmdErr
+}
+
+// ping tells whether bw serve answers at Addr.
+func (s *Serve) ping(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	return s.do(ctx, http.MethodGet, "/status", nil, nil, nil) == nil
+}
+
+// Close ends the bw serve that was started, which forgets the session. One
+// that was running already is left alone.
+func (s *Serve) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil || s.ended() {
+		return nil
+	}
+	if err := s.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-s.exited
+	s.ready = false
+	return nil
+}
+
+// call starts bw serve if need be and sends it a request, decoding the data
+// of the answer into out when out isn't nil.
+func (s *Serve) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	if err := s.start(ctx); err != nil {
+		return err
+	}
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	return s.do(ctx, method, path, query, payload, out)
+}
+
+// list is call for the endpoints that answer with a list, decoding the
+// items of the list into out.
+func (s *Serve) list(ctx context.Context, path string, query url.Values, out interface{}) error {
+	var list struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := s.call(ctx, http.MethodGet, path, query, nil, &list); err != nil {
+		return err
+	}
+	return decodeOutput(list.Data, out)
+}
+
+// do sends a request with a JSON body to bw serve, mapping failures onto
+// the Err values where they fit.
+func (s *Serve) do(ctx context.Context, method, path string, query url.Values, body io.Reader, out interface{}) error {
+	link := s.baseUrl() + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, link, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	data, err := s.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	return decodeServe(data, out)
+}
+
+// send sends req and reads the answer. Answers that aren't a success come
+// out as errors.
+func (s *Serve) send(ctx context.Context, req *http.Request) ([]byte, error) {
+	resp, err := s.client().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	if resp.StatusCode >= 400 {
+		var answer serveResponse
+		_ = json.Unmarshal(data, &answer)
+		return nil, serveError(resp.StatusCode, answer.Message)
+	}
+	return data, nil
+}
+
+// decodeServe unwraps the answer of bw serve, decoding its data into out
+// when out isn't nil.
+func decodeServe(data []byte, out interface{}) error {
+	var answer serveResponse
+	if err := decodeOutput(data, &answer); err != nil {
+		return err
+	}
+	if !answer.Success {
+		return serveError(http.StatusOK, answer.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return decodeOutput(answer.Data, out)
+}
+
+// serveError tells from bw serve's message why a request failed. bw serve
+// words its failures the way the commands do.
+func serveError(status int, message string) error {
+	if message == "" {
+		return &serverError{StatusCode: status}
+	}
+	err := classify(message, 0)
+	for _, known := range []error{ErrNotLoggedIn, ErrLocked, ErrInvalidPassword, ErrNotFound, ErrNetwork} {
+		if err == known {
+			return err
+		}
+	}
+	return &serverError{StatusCode: status, Message: message}
+}
+
+func (s *Serve) Status(ctx context.Context) (*Status, error) {
+	var status struct {
+		Template Status `json:"template"`
+	}
+	if err := s.call(ctx, http.MethodGet, "/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status.Template, nil
+}
+
+// Login isn't something bw serve does, bw login has to be run first.
+func (s *Serve) Login(ctx context.Context, email, password string, twoFactor *TwoFactor) error {
+	return fmt.Errorf("log in with bw login first: %w", ErrUnsupported)
+}
+
+func (s *Serve) LoginAPIKey(ctx context.Context, clientId, clientSecret string) error {
+	return fmt.Errorf("log in with bw login first: %w", ErrUnsupported)
+}
+
+func (s *Serve) Unlock(ctx context.Context, password string) error {
+	body := struct {
+		Password string `json:"password"`
+	}{password}
+	return s.call(ctx, http.MethodPost, "/unlock", nil, body, nil)
+}
+
+func (s *Serve) Lock(ctx context.Context) error {
+	return s.call(ctx, http.MethodPost, "/lock", nil, nil, nil)
+}
+
+// GetServer is the server bw serve reports, with the other URLs read from
+// bw's data.json as bw serve has no say in them.
+func (s *Serve) GetServer(ctx context.Context) (*ServerConfig, error) {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	server := readEnvironment(s.AppDataDir)
+	server.Url = status.ServerUrl
+	if SameServer(server.Url, "") {
+		server.Url = ""
+	}
+	return &server, nil
+}
+
+// SetServer isn't something bw serve does, bw config server has to be run
+// with it stopped.
+func (s *Serve) SetServer(ctx context.Context, server ServerConfig) error {
+	return fmt.Errorf("set the server with bw config server: %w", ErrUnsupported)
+}
+
+func (s *Serve) GetItems(ctx context.Context, filter FilterOptions) ([]Item, error) {
+	query := url.Values{}
+	if filter.Search != "" {
+		query.Set("search", filter.Search)
+	}
+	if filter.Url != "" {
+		query.Set("url", filter.Url)
+	}
+	if filter.FolderId != "" {
+		query.Set("folderid", filter.FolderId)
+	}
+	var items []Item
+	if err := s.list(ctx, "/list/object/items", query, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *Serve) GetItem(ctx context.Context, id string) (*Item, error) {
+	var item *Item
+	if err := s.call(ctx, http.MethodGet, "/object/item/"+url.PathEscape(id), nil, nil, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *Serve) GetFolder(ctx context.Context, id string) (*Folder, error) {
+	var folder *Folder
+	if err := s.call(ctx, http.MethodGet, "/object/folder/"+url.PathEscape(id), nil, nil, &folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+func (s *Serve) GetFolders(ctx context.Context) ([]Folder, error) {
+	var folders []Folder
+	if err := s.list(ctx, "/list/object/folders", nil, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (s *Serve) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	return s.saveFolder(ctx, http.MethodPost, "/object/folder", Folder{Name: name})
+}
+
+func (s *Serve) EditFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	return s.saveFolder(ctx, http.MethodPut, "/object/folder/"+url.PathEscape(folder.Id), folder)
+}
+
+func (s *Serve) saveFolder(ctx context.Context, method, path string, folder Folder) (*Folder, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{folder.Name}
+	var saved *Folder
+	if err := s.call(ctx, method, path, nil, body, &saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (s *Serve) DeleteFolder(ctx context.Context, id string) error {
+	return s.call(ctx, http.MethodDelete, "/object/folder/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// CreateItem fills bw's item template with item and creates it, as
+// Context does.
+func (s *Serve) CreateItem(ctx context.Context, item Item) (*Item, error) {
+	var template json.RawMessage
+	if err := s.call(ctx, http.MethodGet, "/object/template/item", nil, nil, &template); err != nil {
+		return nil, err
+	}
+	payload, err := templatePayload(template, item)
+	if err != nil {
+		return nil, err
+	}
+	var created *Item
+	if err := s.call(ctx, http.MethodPost, "/object/item", nil, json.RawMessage(payload), &created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *Serve) EditItem(ctx context.Context, item Item) (*Item, error) {
+	var edited *Item
+	if err := s.call(ctx, http.MethodPut, "/object/item/"+url.PathEscape(item.Id), nil, item, &edited); err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+func (s *Serve) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	query := url.Values{}
+	if permanent {
+		query.Set("permanent", "true")
+	}
+	return s.call(ctx, http.MethodDelete, "/object/item/"+url.PathEscape(id), query, nil, nil)
+}
+
+func (s *Serve) RestoreItem(ctx context.Context, id string) error {
+	return s.call(ctx, http.MethodPost, "/restore/item/"+url.PathEscape(id), nil, nil, nil)
+}
+
+func (s *Serve) GetTrash(ctx context.Context) ([]Item, error) {
+	var items []Item
+	if err := s.list(ctx, "/list/object/items", url.Values{"trash": {"true"}}, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// DownloadAttachment saves the attachment, which bw serve answers with as
+// is rather than wrapped.
+func (s *Serve) DownloadAttachment(ctx context.Context, itemId, attachmentId, path string) error {
+	if err := s.start(ctx); err != nil {
+		return err
+	}
+	link := s.baseUrl() + "/object/attachment/" + url.PathEscape(attachmentId) + "?" + url.Values{"itemid": {itemId}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	data, err := s.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (s *Serve) UploadAttachment(ctx context.Context, itemId, path string) (*Item, error) {
+	if err := s.start(ctx); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+	link := s.baseUrl() + "/attachment?" + url.Values{"itemid": {itemId}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, link, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	data, err := s.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var item *Item
+	if err := decodeServe(data, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *Serve) DeleteAttachment(ctx context.Context, itemId, attachmentId string) error {
+	query := url.Values{"itemid": {itemId}}
+	return s.call(ctx, http.MethodDelete, "/object/attachment/"+url.PathEscape(attachmentId), query, nil, nil)
+}
+
+func (s *Serve) Sync(ctx context.Context) error {
+	return s.call(ctx, http.MethodPost, "/sync", nil, nil, nil)
+}
+
+func (s *Serve) LastSync(ctx context.Context) (*time.Time, error) {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return status.LastSync, nil
+}
+
+func (s *Serve) GetSends(ctx context.Context) ([]Send, error) {
+	var sends []Send
+	if err := s.list(ctx, "/list/object/send", nil, &sends); err != nil {
+		return nil, err
+	}
+	return sends, nil
+}
+
+// CreateSend fills bw's text send template and creates it. bw serve takes
+// no files, so file sends need the CLI.
+func (s *Serve) CreateSend(ctx context.Context, send Send) (*Send, error) {
+	if send.Type == SendTypeFile {
+		return nil, ErrUnsupported
+	}
+	var template json.RawMessage
+	if err := s.call(ctx, http.MethodGet, "/object/template/send.text", nil, nil, &template); err != nil {
+		return nil, err
+	}
+	payload, err := templatePayload(template, send)
+	if err != nil {
+		return nil, err
+	}
+	var created *Send
+	if err := s.call(ctx, http.MethodPost, "/object/send", nil, json.RawMessage(payload), &created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *Serve) EditSend(ctx context.Context, send Send) (*Send, error) {
+	var edited *Send
+	if err := s.call(ctx, http.MethodPut, "/object/send/"+url.PathEscape(send.Id), nil, send, &edited); err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+func (s *Serve) DeleteSend(ctx context.Context, id string) error {
+	return s.call(ctx, http.MethodDelete, "/object/send/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,231 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// serveStub plays bw serve for a vault with one item, unlocked with
+// "hunter2".
+func serveStub(t *testing.T) http.Handler {
+	t.Helper()
+	unlocked := false
+	answer := func(w http.ResponseWriter, status int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+	locked := func(w http.ResponseWriter) bool {
+		if !unlocked {
+			answer(w, http.StatusBadRequest, `{"success": false, "message": "Vault is locked."}`)
+		}
+		return !unlocked
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		status := StatusLocked
+		if unlocked {
+			status = StatusUnlocked
+		}
+		answer(w, http.StatusOK, `{"success": true, "data": {"object": "template", "template": {
+			"serverUrl": "https://vault.example.com", "userEmail": "user@example.com", "status": "`+status+`"}}}`)
+	})
+	mux.HandleFunc("/unlock", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Password string `json:"password"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil {
+			answer(w, http.StatusBadRequest, `{"success": false}`)
+			return
+		}
+		if body.Password != "hunter2" {
+			answer(w, http.StatusBadRequest, `{"success": false, "message": "Invalid master password."}`)
+			return
+		}
+		unlocked = true
+		answer(w, http.StatusOK, `{"success": true, "data": {"object": "message", "title": "Your vault is now unlocked!"}}`)
+	})
+	mux.HandleFunc("/list/object/items", func(w http.ResponseWriter, r *http.Request) {
+		if locked(w) {
+			return
+		}
+		items := `[{"id": "i1", "type": 1, "name": "GitHub", "login": {"username": "octo", "password": "hunter2"}}]`
+		if search := r.URL.Query().Get("search"); search != "" && !strings.Contains("github", strings.ToLower(search)) {
+			items = `[]`
+		}
+		answer(w, http.StatusOK, `{"success": true, "data": {"object": "list", "data": `+items+`}}`)
+	})
+	mux.HandleFunc("/object/item/", func(w http.ResponseWriter, r *http.Request) {
+		if locked(w) {
+			return
+		}
+		if strings.TrimPrefix(r.URL.Path, "/object/item/") != "i1" {
+			answer(w, http.StatusNotFound, `{"success": false, "message": "Not found."}`)
+			return
+		}
+		answer(w, http.StatusOK, `{"success": true, "data": {"id": "i1", "type": 1, "name": "GitHub", "login": {"username": "octo", "password": "hunter2"}}}`)
+	})
+	mux.HandleFunc("/sync", func(w http.ResponseWriter, r *http.Request) {
+		answer(w, http.StatusInternalServerError, ``)
+	})
+	mux.HandleFunc("/lock", func(w http.ResponseWriter, r *http.Request) {
+		// a 200 that didn't work out
+		answer(w, http.StatusOK, `{"success": false, "message": "Something went wrong."}`)
+	})
+	return mux
+}
+
+// newServeStub is a Serve talking to serveStub over TCP. Nothing is
+// started, the stub already serves its Addr.
+func newServeStub(t *testing.T) *Serve {
+	t.Helper()
+	server := httptest.NewServer(serveStub(t))
+	t.Cleanup(server.Close)
+	return &Serve{Addr: strings.TrimPrefix(server.URL, "http://"), Client: server.Client()}
+}
+
+func TestServeUnlockAndRead(t *testing.T) {
+	s := newServeStub(t)
+	ctx := context.Background()
+	status, err := s.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.Status != StatusLocked || status.UserEmail != "user@example.com" {
+		t.Errorf("Status = %+v", status)
+	}
+	if _, err := s.GetItems(ctx, FilterOptions{}); !errors.Is(err, ErrLocked) {
+		t.Errorf("GetItems while locked = %v, want ErrLocked", err)
+	}
+	if err := s.Unlock(ctx, "wrong"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Unlock with the wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if err := s.Unlock(ctx, "hunter2"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if status, err := s.Status(ctx); err != nil || status.Status != StatusUnlocked {
+		t.Errorf("Status after unlocking = %+v, %v", status, err)
+	}
+
+	items, err := s.GetItems(ctx, FilterOptions{})
+	if err != nil {
+		t.Fatalf("GetItems: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "GitHub" {
+		t.Errorf("GetItems = %+v", items)
+	}
+	if items, err := s.GetItems(ctx, FilterOptions{Search: "gitlab"}); err != nil || len(items) != 0 {
+		t.Errorf("GetItems searching for gitlab = %+v, %v", items, err)
+	}
+	item, err := s.GetItem(ctx, "i1")
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if item.Login.Username != "octo" || item.Login.Password != "hunter2" {
+		t.Errorf("GetItem = %+v", item.Login)
+	}
+	if _, err := s.GetItem(ctx, "i2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetItem of a missing item = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	s := newServeStub(t)
+	ctx := context.Background()
+
+	// an error status without a message
+	err := s.Sync(ctx)
+	var srvErr *serverError
+	if !errors.As(err, &srvErr) || srvErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Sync = %v, want a 500 serverError", err)
+	}
+	// success false in a 200
+	err = s.Lock(ctx)
+	if !errors.As(err, &srvErr) || srvErr.Message != "Something went wrong." {
+		t.Errorf("Lock = %v, want the message bw serve gave", err)
+	}
+	// nothing serves Addr, and nothing is to be started
+	s = &Serve{Addr: "127.0.0.1:1"}
+	if _, err := s.Status(ctx); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Status with nothing serving = %v, want ErrNetwork", err)
+	}
+}
+
+func TestServeOverSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix sockets")
+	}
+	// socket paths are short, t.TempDir can be too long for one
+	dir, err := os.MkdirTemp("", "bwtui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "serve.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(serveStub(t))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	s := NewServe("unix:" + path)
+	s.Binary = ""
+	if s.OverTCP() {
+		t.Error("a unix: Addr is over TCP")
+	}
+	if status, err := s.Status(context.Background()); err != nil || status.Status != StatusLocked {
+		t.Errorf("Status = %+v, %v", status, err)
+	}
+}
+
+func TestServeStartsOnSocket(t *testing.T) {
+	// a bw serve that fails right away, after its argv is recorded
+	recorded := fakeBW(t, "")
+	script := filepath.Join(recorded, "bw")
+	if err := os.WriteFile(script, append(readFile(t, script), []byte("echo 'Vault is locked.' >&2\nexit 1\n")...), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "bwtui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "private", "serve.sock")
+
+	s := NewServe("unix:" + path)
+	if _, err := s.Status(context.Background()); !errors.Is(err, ErrLocked) {
+		t.Errorf("Status = %v, want the ErrLocked bw exited with", err)
+	}
+	if argv := readRecorded(t, recorded, "argv"); argv != "serve\n--hostname\nunix:"+path+"\n" {
+		t.Errorf("argv = %q", argv)
+	}
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o700 {
+		t.Errorf("socket directory mode = %v, want 0700", mode)
+	}
+}
+
+func TestDefaultServeAddr(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if addr := DefaultServeAddr(); addr != "unix:/run/user/1000/bwtui/serve.sock" {
+		t.Errorf("DefaultServeAddr = %q", addr)
+	}
+	if s := NewServe("localhost:8087"); !s.OverTCP() {
+		t.Error("localhost:8087 isn't over TCP")
+	}
+}
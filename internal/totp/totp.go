@@ -0,0 +1,136 @@
+// Package totp computes RFC 6238 one-time codes from the authenticator
+// secrets Bitwarden stores on login items.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+// steamAlphabet is what Steam Guard codes are spelled with.
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+type Key struct {
+	Secret    []byte
+	Algorithm string // SHA1, SHA256 or SHA512
+	Digits    int
+	Period    int
+	// Steam keys produce five letter Steam Guard codes instead of digits
+	Steam bool
+}
+
+// Parse reads a secret in any of the forms Bitwarden accepts: a bare base32
+// secret, an otpauth:// URI or a steam:// secret.
+func Parse(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+	key := &Key{Algorithm: "SHA1", Digits: 6, Period: 30}
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "otpauth://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, ErrInvalidSecret
+		}
+		q := u.Query()
+		s = q.Get("secret")
+		if a := strings.ToUpper(q.Get("algorithm")); a != "" {
+			key.Algorithm = a
+		}
+		if d := q.Get("digits"); d != "" {
+			digits, err := strconv.Atoi(d)
+			if err != nil || digits < 1 || digits > 10 {
+				return nil, ErrInvalidSecret
+			}
+			key.Digits = digits
+		}
+		if p := q.Get("period"); p != "" {
+			period, err := strconv.Atoi(p)
+			if err != nil || period < 1 {
+				return nil, ErrInvalidSecret
+			}
+			key.Period = period
+		}
+		if strings.EqualFold(q.Get("encoder"), "steam") {
+			key.Steam = true
+			key.Digits = 5
+		}
+	case strings.HasPrefix(lower, "steam://"):
+		s = s[len("steam://"):]
+		key.Steam = true
+		key.Digits = 5
+	}
+	if key.hash() == nil {
+		return nil, ErrInvalidSecret
+	}
+	secret, err := decodeBase32(s)
+	if err != nil || len(secret) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	key.Secret = secret
+	return key, nil
+}
+
+func decodeBase32(s string) ([]byte, error) {
+	s = strings.ToUpper(s)
+	s = strings.NewReplacer(" ", "", "-", "", "=", "").Replace(s)
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+}
+
+func (k *Key) hash() func() hash.Hash {
+	switch k.Algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// Code returns the code valid at t.
+func (k *Key) Code(t time.Time) string {
+	counter := uint64(t.Unix()) / uint64(k.Period)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(k.hash(), k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	if k.Steam {
+		code := make([]byte, k.Digits)
+		for i := range code {
+			code[i] = steamAlphabet[value%uint32(len(steamAlphabet))]
+			value /= uint32(len(steamAlphabet))
+		}
+		return string(code)
+	}
+	mod := uint64(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, uint64(value)%mod)
+}
+
+// Remaining is how long the code valid at t stays valid.
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period)
+	left := period - t.Unix()%period
+	return time.Duration(left) * time.Second
+}
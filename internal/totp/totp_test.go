@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// The seeds of RFC 6238 Appendix B, in base32.
+const (
+	seedSHA1   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	seedSHA256 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+	seedSHA512 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA"
+)
+
+func TestRFC6238(t *testing.T) {
+	tests := []struct {
+		time                 int64
+		sha1, sha256, sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+	for _, test := range tests {
+		for _, vector := range []struct {
+			algorithm, seed, want string
+		}{
+			{"SHA1", seedSHA1, test.sha1},
+			{"SHA256", seedSHA256, test.sha256},
+			{"SHA512", seedSHA512, test.sha512},
+		} {
+			key, err := Parse("otpauth://totp/test?secret=" + vector.seed + "&algorithm=" + vector.algorithm + "&digits=8")
+			if err != nil {
+				t.Fatalf("Parse %s: %v", vector.algorithm, err)
+			}
+			if got := key.Code(time.Unix(test.time, 0)); got != vector.want {
+				t.Errorf("%s code at %d = %s, want %s", vector.algorithm, test.time, got, vector.want)
+			}
+		}
+	}
+}
+
+func TestBareSecret(t *testing.T) {
+	// lower case and spaced out, as authenticator setups often show it
+	key, err := Parse("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if key.Algorithm != "SHA1" || key.Digits != 6 || key.Period != 30 {
+		t.Errorf("defaults = %s, %d digits, %ds", key.Algorithm, key.Digits, key.Period)
+	}
+	if got := key.Code(time.Unix(59, 0)); got != "287082" {
+		t.Errorf("code = %s, want 287082", got)
+	}
+}
+
+func TestSteam(t *testing.T) {
+	for _, secret := range []string{"steam://" + seedSHA1, "otpauth://totp/Steam:user?secret=" + seedSHA1 + "&encoder=steam"} {
+		key, err := Parse(secret)
+		if err != nil {
+			t.Fatalf("Parse %s: %v", secret, err)
+		}
+		if got := key.Code(time.Unix(59, 0)); got != "PV9M4" {
+			t.Errorf("%s code at 59 = %s, want PV9M4", secret, got)
+		}
+		if got := key.Code(time.Unix(1234567890, 0)); got != "VHHQY" {
+			t.Errorf("%s code at 1234567890 = %s, want VHHQY", secret, got)
+		}
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	key, err := Parse("otpauth://totp/Example:user@example.com?secret=" + seedSHA256 + "&issuer=Example&algorithm=sha256&digits=6&period=60")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if key.Algorithm != "SHA256" || key.Digits != 6 || key.Period != 60 {
+		t.Errorf("key = %s, %d digits, %ds", key.Algorithm, key.Digits, key.Period)
+	}
+	if got := key.Code(time.Unix(1234567890, 0)); got != "450756" {
+		t.Errorf("code = %s, want 450756", got)
+	}
+	if got := key.Remaining(time.Unix(1234567890, 0)); got != 30*time.Second {
+		t.Errorf("remaining = %v, want 30s", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, secret := range []string{
+		"",
+		"not base32!",
+		"otpauth://totp/test",
+		"otpauth://totp/test?secret=" + seedSHA1 + "&algorithm=MD5",
+		"otpauth://totp/test?secret=" + seedSHA1 + "&digits=0",
+		"otpauth://totp/test?secret=" + seedSHA1 + "&digits=eight",
+		"otpauth://totp/test?secret=" + seedSHA1 + "&period=-30",
+		"otpauth://totp/test?secret=1891",
+	} {
+		if _, err := Parse(secret); !errors.Is(err, ErrInvalidSecret) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidSecret", secret, err)
+		}
+	}
+}
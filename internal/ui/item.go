@@ -2,13 +2,16 @@ package item
 
 import (
 	bw "bitwarden-tui/internal"
+	"bitwarden-tui/internal/clipboard"
+	"bitwarden-tui/internal/totp"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -19,6 +22,65 @@ var (
 
 type statusTimeoutMsg struct{}
 
+// TotpTickMsg redraws the TOTP code and countdown. id tells ticks started
+// for a previous item apart, so that only one chain of ticks runs. Owners
+// hand it to the model whatever view is showing, or the chain ends.
+type TotpTickMsg struct{ id int }
+
+// SaveMsg asks the owner of the model to store an edited item.
+type SaveMsg struct {
+	Item bw.Item
+}
+
+// DeleteMsg asks the owner of the model to delete the shown item.
+type DeleteMsg struct {
+	Item      bw.Item
+	Permanent bool
+}
+
+// MoveMsg asks the owner of the model to move the shown item to another
+// folder.
+type MoveMsg struct {
+	Item bw.Item
+}
+
+// DownloadMsg asks the owner of the model to save an attachment of the
+// shown item.
+type DownloadMsg struct {
+	Item       bw.Item
+	Attachment bw.Attachment
+}
+
+// AttachMsg asks the owner of the model to attach a file to the shown item.
+type AttachMsg struct {
+	Item bw.Item
+}
+
+// DeleteAttachmentMsg asks the owner of the model to delete an attachment
+// of the shown item.
+type DeleteAttachmentMsg struct {
+	Item       bw.Item
+	Attachment bw.Attachment
+}
+
+// SendMsg asks the owner of the model to share the password of the shown
+// item as a Bitwarden Send.
+type SendMsg struct {
+	Item bw.Item
+}
+
+// GenerateMsg asks the owner of the model for a generated password for the
+// property being edited. Hand it back with SetEditValue.
+type GenerateMsg struct{}
+
+// CopiedMsg tells the owner of the model that a property went into the
+// clipboard, which Clipboard clears once its timeout passes.
+type CopiedMsg struct{}
+
+// notesNewline stands in for line breaks while notes are edited on a single
+// line.
+const notesNewline = "↵"
+
 type Styles struct {
 	Title            lipgloss.Style
 	Subtitle         lipgloss.Style
@@ -27,13 +89,24 @@ type Styles struct {
 }
 
 type ItemKeyMap struct {
-	Up            key.Binding
-	Down          key.Binding
-	Back          key.Binding
-	Copy          key.Binding
-	Quit          key.Binding
-	OpenFullHelp  key.Binding
-	CloseFullHelp key.Binding
+	Up               key.Binding
+	Down             key.Binding
+	Back             key.Binding
+	Copy             key.Binding
+	Edit             key.Binding
+	Save             key.Binding
+	CancelEdit       key.Binding
+	Generate         key.Binding
+	Delete           key.Binding
+	Purge            key.Binding
+	Move             key.Binding
+	Download         key.Binding
+	Attach           key.Binding
+	DeleteAttachment key.Binding
+	Send             key.Binding
+	Quit             key.Binding
+	OpenFullHelp     key.Binding
+	CloseFullHelp    key.Binding
 }
 
 func newItemKeyMap() *ItemKeyMap {
@@ -54,6 +127,50 @@ func newItemKeyMap() *ItemKeyMap {
 			key.WithKeys("enter", "c"),
 			key.WithHelp("enter/c", "copy property"),
 		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit property"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "save"),
+		),
+		CancelEdit: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		Generate: key.NewBinding(
+			key.WithKeys("ctrl+g"),
+			key.WithHelp("ctrl+g", "generate"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "move to trash"),
+		),
+		Purge: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "delete permanently"),
+		),
+		Move: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "move to folder"),
+		),
+		Download: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "download attachment"),
+		),
+		Attach: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "attach file"),
+		),
+		DeleteAttachment: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "delete attachment"),
+		),
+		Send: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "send password"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q"),
 			key.WithHelp("q", "quit"),
@@ -71,40 +188,49 @@ func newItemKeyMap() *ItemKeyMap {
 
 func (k ItemKeyMap) ShortHelp() []key.Binding {
 	return []key.Binding{
-		k.Up, k.Down, k.Copy, k.OpenFullHelp,
+		k.Up, k.Down, k.Copy, k.Edit, k.OpenFullHelp,
 	}
 }
 func (k ItemKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Back},
-		{k.Copy},
+		{k.Copy, k.Edit},
+		{k.Move, k.Delete, k.Purge, k.Send},
+		{k.Download, k.Attach, k.DeleteAttachment},
 		{k.CloseFullHelp, k.Quit},
 	}
 }
 
-type SelectedProperty int
+// editKeyMap is the help shown while a property is being edited.
+type editKeyMap ItemKeyMap
 
-const (
-	USERNAME SelectedProperty = iota
-	PASSWORD
-	FIELDS
-	URI
-)
+func (k editKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Save, k.CancelEdit, k.Generate}
+}
+func (k editKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Save, k.CancelEdit, k.Generate}}
+}
 
 type Model struct {
 	Item   bw.Item
 	Help   help.Model
 	KeyMap *ItemKeyMap
 	Styles Styles
+	// Clipboard takes copied properties back out after its timeout
+	Clipboard *clipboard.Guard
 
-	cursor             SelectedProperty
-	height             int
-	width              int
-	selectedUriIndex   uint8
-	selectedFieldIndex uint8
+	// cursor indexes the flattened properties of the item's layout
+	cursor int
+	height int
+	width  int
 
 	statusMessage      string
 	statusMessageTimer *time.Timer
+
+	editing   bool
+	editInput textinput.Model
+
+	totpTicks int
 }
 
 func (m *Model) SetSize(width, height int) {
@@ -125,72 +251,152 @@ func (m *Model) setSize(width, height int) {
 	m.Help.Width = width
 }
 
+// SetItem shows a new item and moves the cursor to its first property. The
+// returned command keeps the TOTP code of login items current.
+func (m *Model) SetItem(item bw.Item) tea.Cmd {
+	m.Item = item
+	m.cursor = 0
+	m.editing = false
+	m.totpTicks++
+	return m.totpTick()
+}
+
+// UpdateItem shows a newer version of the same item, keeping the cursor on
+// the same row where it still exists.
+func (m *Model) UpdateItem(item bw.Item) {
+	m.Item = item
+	if count := len(m.properties()); m.cursor >= count && count > 0 {
+		m.cursor = count - 1
+	}
+}
+
+// Reset forgets the item and anything being typed, for when the vault
+// locks.
+func (m *Model) Reset() {
+	m.Item = bw.Item{}
+	m.cursor = 0
+	m.editing = false
+	m.editInput = textinput.Model{}
+	m.totpTicks++
+	m.hideStatusMessage()
+}
+
+func (m *Model) totpTick() tea.Cmd {
+	if m.Item.Login.Totp == "" {
+		return nil
+	}
+	id := m.totpTicks
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return TotpTickMsg{id: id}
+	})
+}
+
+// Editing reports whether a property is being edited. The model then takes
+// all key presses, including esc.
+func (m *Model) Editing() bool {
+	return m.editing
+}
+
+func (m *Model) startEdit() tea.Cmd {
+	p, ok := m.selected()
+	if !ok || p.set == nil {
+		return m.NewStatusMessage("can't edit this property")
+	}
+	m.editInput = textinput.New()
+	m.editInput.Prompt = ""
+	value := p.value
+	if p.kind == NOTES {
+		value = strings.ReplaceAll(value, "\n", notesNewline)
+		m.editInput.Placeholder = "notes, " + notesNewline + " for a line break"
+	}
+	m.editInput.SetValue(value)
+	m.editing = true
+	return m.editInput.Focus()
+}
+
+// SetEditValue replaces the text of the property being edited.
+func (m *Model) SetEditValue(v string) {
+	if m.editing {
+		m.editInput.SetValue(v)
+		m.editInput.CursorEnd()
+	}
+}
+
+// editingSecret reports whether the property being edited is a password
+// or another hidden value, the ones a generated password makes sense for.
+func (m *Model) editingSecret() bool {
+	p, ok := m.selected()
+	return m.editing && ok && (p.kind == PASSWORD || (p.kind == FIELDS && p.hidden))
+}
+
+// saveEdit applies the edited value to a copy of the item and hands it over
+// in a SaveMsg. Item itself changes once the owner calls SetItem.
+func (m *Model) saveEdit() tea.Cmd {
+	m.editing = false
+	p, ok := m.selected()
+	if !ok || p.set == nil {
+		return nil
+	}
+	value := m.editInput.Value()
+	if p.kind == NOTES {
+		value = strings.ReplaceAll(value, notesNewline, "\n")
+	}
+	if value == p.value {
+		return nil
+	}
+	edited := m.Item
+	edited.Fields = append([]bw.Field(nil), m.Item.Fields...)
+	edited.Login.Uris = append([]bw.Uri(nil), m.Item.Login.Uris...)
+	p.set(&edited, value)
+	return func() tea.Msg {
+		return SaveMsg{Item: edited}
+	}
+}
+
+func (m *Model) properties() []property {
+	var props []property
+	for _, s := range layout(m.Item) {
+		props = append(props, s.props...)
+	}
+	return props
+}
+
+func (m *Model) selected() (property, bool) {
+	props := m.properties()
+	if m.cursor < 0 || m.cursor >= len(props) {
+		return property{}, false
+	}
+	return props[m.cursor], true
+}
+
+// selectedAttachment is the attachment under the cursor, if any.
+func (m *Model) selectedAttachment() (bw.Attachment, bool) {
+	p, ok := m.selected()
+	if !ok || p.kind != ATTACHMENT {
+		return bw.Attachment{}, false
+	}
+	return m.Item.Attachments[p.index], true
+}
+
 func (m *Model) Cursor() SelectedProperty {
-	return m.cursor
+	p, _ := m.selected()
+	return p.kind
 }
 
 func (m *Model) CursorDown() {
-	uriLen := len(m.Item.Login.Uris)
-	fieldLen := len(m.Item.Fields)
-	switch m.cursor {
-	case USERNAME:
-		m.cursor = PASSWORD
-	case PASSWORD:
-		if fieldLen > 0 {
-			m.cursor = FIELDS
-		} else if uriLen > 0 {
-			m.cursor = URI
-		} else {
-			m.cursor = USERNAME
-		}
-		m.selectedUriIndex = 0
-		m.selectedFieldIndex = 0
-	case FIELDS:
-		if m.selectedFieldIndex < uint8(fieldLen)-1 {
-			m.selectedFieldIndex += 1
-		} else {
-			m.cursor = URI
-		}
-	case URI:
-		if m.selectedUriIndex < uint8(uriLen)-1 {
-			m.selectedUriIndex += 1
-		} else {
-			m.cursor = USERNAME
-		}
+	count := len(m.properties())
+	if count == 0 {
+		return
 	}
+	m.cursor = (m.cursor + 1) % count
 }
 
 func (m *Model) CursorUp() {
-	uriLen := len(m.Item.Login.Uris)
-	fieldLen := len(m.Item.Fields)
-	switch m.cursor {
-	case USERNAME:
-		if uriLen > 0 {
-			m.cursor = URI
-		} else {
-			m.cursor = PASSWORD
-		}
-		m.selectedUriIndex = uint8(uriLen) - 1
-		m.selectedFieldIndex = uint8(fieldLen) - 1
-	case PASSWORD:
-		m.cursor = USERNAME
-	case FIELDS:
-		if m.selectedFieldIndex == 0 {
-			m.cursor = PASSWORD
-		} else {
-			m.selectedFieldIndex--
-		}
-	case URI:
-		if m.selectedUriIndex == 0 {
-			if fieldLen > 0 {
-				m.cursor = FIELDS
-			} else {
-				m.cursor = PASSWORD
-			}
-		} else {
-			m.selectedUriIndex--
-		}
+	count := len(m.properties())
+	if count == 0 {
+		return
 	}
+	m.cursor = (m.cursor - 1 + count) % count
 }
 
 func (m *Model) NewStatusMessage(s string) tea.Cmd {
@@ -213,30 +419,23 @@ func (m *Model) hideStatusMessage() {
 }
 
 func (m *Model) copySelected() tea.Cmd {
-	if clipboard.Unsupported {
+	if m.Clipboard.Unsupported() {
 		return m.NewStatusMessage("clipboard unsupported!")
 	}
-	var toCopy string
-	var prop string
-	switch m.cursor {
-	case USERNAME:
-		toCopy = m.Item.Login.Username
-		prop = "username"
-	case PASSWORD:
-		toCopy = m.Item.Login.Password
-		prop = "password"
-	case FIELDS:
-		toCopy = m.Item.Fields[m.selectedFieldIndex].Value
-		prop = "field"
-	case URI:
-		toCopy = m.Item.Login.Uris[m.selectedUriIndex].Uri
-		prop = "url"
-	}
-	err := clipboard.WriteAll(toCopy)
+	p, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	if p.value == "" {
+		return m.NewStatusMessage("nothing to copy")
+	}
+	err := m.Clipboard.Copy(p.value)
 	if err != nil {
 		return m.NewStatusMessage("failed to copy!")
 	}
-	return m.NewStatusMessage("copied " + prop)
+	return tea.Batch(m.NewStatusMessage("copied "+p.copyName()), func() tea.Msg {
+		return CopiedMsg{}
+	})
 }
 
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
@@ -244,125 +443,177 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case statusTimeoutMsg:
 		m.hideStatusMessage()
+	case TotpTickMsg:
+		if msg.id == m.totpTicks {
+			cmds = append(cmds, m.totpTick())
+		}
 	case tea.KeyMsg:
+		if m.editing {
+			switch {
+			case key.Matches(msg, m.KeyMap.Save):
+				cmds = append(cmds, m.saveEdit())
+			case key.Matches(msg, m.KeyMap.CancelEdit):
+				m.editing = false
+			case key.Matches(msg, m.KeyMap.Generate) && m.editingSecret():
+				cmds = append(cmds, func() tea.Msg { return GenerateMsg{} })
+			default:
+				var cmd tea.Cmd
+				m.editInput, cmd = m.editInput.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+			return m, tea.Batch(cmds...)
+		}
 		switch {
 		case key.Matches(msg, m.KeyMap.Quit):
 			return m, tea.Quit
 		case key.Matches(msg, m.KeyMap.Back):
-			m.cursor = USERNAME
+			m.cursor = 0
+			// nothing shows the countdown until the next SetItem
+			m.totpTicks++
 		case key.Matches(msg, m.KeyMap.Down):
 			m.CursorDown()
 		case key.Matches(msg, m.KeyMap.Up):
 			m.CursorUp()
 		case key.Matches(msg, m.KeyMap.OpenFullHelp), key.Matches(msg, m.KeyMap.CloseFullHelp):
 			m.Help.ShowAll = !m.Help.ShowAll
+		case key.Matches(msg, m.KeyMap.Copy) && m.Cursor() == ATTACHMENT,
+			key.Matches(msg, m.KeyMap.Download):
+			if attachment, ok := m.selectedAttachment(); ok {
+				downloadMsg := DownloadMsg{Item: m.Item, Attachment: attachment}
+				cmds = append(cmds, func() tea.Msg { return downloadMsg })
+			}
 		case key.Matches(msg, m.KeyMap.Copy):
 			cmds = append(cmds, m.copySelected())
+		case key.Matches(msg, m.KeyMap.Send) && m.Item.Login.Password != "":
+			sendMsg := SendMsg{Item: m.Item}
+			cmds = append(cmds, func() tea.Msg { return sendMsg })
+		case key.Matches(msg, m.KeyMap.Attach):
+			attachMsg := AttachMsg{Item: m.Item}
+			cmds = append(cmds, func() tea.Msg { return attachMsg })
+		case key.Matches(msg, m.KeyMap.DeleteAttachment):
+			if attachment, ok := m.selectedAttachment(); ok {
+				deleteMsg := DeleteAttachmentMsg{Item: m.Item, Attachment: attachment}
+				cmds = append(cmds, func() tea.Msg { return deleteMsg })
+			}
+		case key.Matches(msg, m.KeyMap.Edit):
+			cmds = append(cmds, m.startEdit())
+		case key.Matches(msg, m.KeyMap.Move):
+			moveMsg := MoveMsg{Item: m.Item}
+			cmds = append(cmds, func() tea.Msg { return moveMsg })
+		case key.Matches(msg, m.KeyMap.Delete), key.Matches(msg, m.KeyMap.Purge):
+			deleteMsg := DeleteMsg{Item: m.Item, Permanent: key.Matches(msg, m.KeyMap.Purge)}
+			cmds = append(cmds, func() tea.Msg { return deleteMsg })
+		}
+	default:
+		if m.editing {
+			var cmd tea.Cmd
+			m.editInput, cmd = m.editInput.Update(msg)
+			cmds = append(cmds, cmd)
 		}
 	}
 	return m, tea.Batch(cmds...)
 }
 
-func (m *Model) renderCreds() string {
-	userLabel := m.Styles.Label.Render("Username")
-	passwordLabel := m.Styles.Label.Render("Password")
-
+func (m *Model) renderSection(s section, offset int) string {
 	var b strings.Builder
-	usernameValue := m.Item.Login.Username
-	if usernameValue == "" {
-		usernameValue = "(no username)"
-	}
-	if m.cursor == USERNAME {
-		b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + userLabel)
-	} else {
-		b.WriteString("  " + userLabel)
-	}
-	if m.cursor == USERNAME {
-		b.WriteString(m.Styles.SelectedProperty.Render(usernameValue))
-	} else {
-		b.WriteString(usernameValue)
-	}
-
-	passwordValue := m.Item.Login.Password
-	if passwordValue == "" {
-		passwordValue = "(no password)"
+	if s.title != "" {
+		b.WriteString(m.Styles.Subtitle.Render(s.title) + "\n")
 	}
-	b.WriteString("\n")
-	if m.cursor == PASSWORD {
-		b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + passwordLabel)
-	} else {
-		b.WriteString("  " + passwordLabel)
-	}
-	if m.cursor == PASSWORD {
-		b.WriteString(m.Styles.SelectedProperty.Render(m.Item.Login.Password))
-	} else {
-		b.WriteString(strings.Repeat("•", 4))
-	}
-
-	return b.String()
-}
-
-func (m *Model) renderFields() string {
-	var b strings.Builder
-
-	maxTitleChars := getMax(bw.Map(m.Item.Fields, func(f bw.Field) string { return f.Name }))
-	for i, f := range m.Item.Fields {
-		titleChars := len(f.Name)
-		remainingChars := maxTitleChars - titleChars
-		isSelected := m.selectedFieldIndex == uint8(i) && m.cursor == FIELDS
-
-		val := f.Value
-		if f.Value == "" {
-			val = "(empty)"
-		}
-
-		b.WriteString("\n")
-		if isSelected {
-			b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + m.Styles.Label.Render(f.Name))
-		} else {
-			b.WriteString("  " + m.Styles.Label.Render(f.Name))
-		}
-
-		b.WriteString(strings.Repeat(" ", remainingChars))
-		if isSelected {
-			b.WriteString(m.Styles.SelectedProperty.Render(val))
-		} else {
-			if f.Type == 1 && f.Value != "" {
-				b.WriteString(strings.Repeat("•", 4))
+	switch s.kind {
+	case labelledSection:
+		maxLabelChars := getMax(mapLabels(s.props))
+		for i, p := range s.props {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			isSelected := m.cursor == offset+i
+			if isSelected {
+				b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + m.Styles.Label.Render(p.label))
 			} else {
-				b.WriteString(val)
+				b.WriteString("  " + m.Styles.Label.Render(p.label))
+			}
+			b.WriteString(strings.Repeat(" ", maxLabelChars-len(p.label)))
+			if isSelected && m.editing {
+				b.WriteString(m.editInput.View())
+			} else {
+				b.WriteString(m.renderValue(p, isSelected))
+			}
+			if p.kind == TOTP && p.value != "" {
+				b.WriteString(" " + m.renderCountdown())
 			}
 		}
-	}
-
-	return b.String()
-}
-
-func (m *Model) renderUri() string {
-	var b strings.Builder
-	b.WriteString(m.Styles.Subtitle.Render("URIs") + "\n")
-	for i, u := range m.Item.Login.Uris {
-		parsed, err := url.Parse(u.Uri)
-		if m.cursor == URI && m.selectedUriIndex == uint8(i) {
-			b.WriteString(m.Styles.SelectedProperty.Render("🢒 "+u.Uri) + "\n")
-		} else {
+	case linkSection:
+		for i, p := range s.props {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			if m.cursor == offset+i {
+				if m.editing {
+					b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + m.editInput.View())
+				} else {
+					b.WriteString(m.Styles.SelectedProperty.Render("🢒 " + p.value))
+				}
+				continue
+			}
 			b.WriteString("  ")
+			parsed, err := url.Parse(p.value)
 			if err != nil || parsed.Host == "" {
-				b.WriteString(u.Uri)
+				b.WriteString(p.value)
 			} else {
 				b.WriteString(parsed.Host)
 			}
-			b.WriteString("\n")
+		}
+	case textSection:
+		for i, p := range s.props {
+			if m.cursor == offset+i && m.editing {
+				b.WriteString(m.Styles.SelectedProperty.Render("🢒 ") + m.editInput.View())
+			} else if p.value == "" && m.cursor == offset+i {
+				b.WriteString(marginLeft.Render(m.Styles.SelectedProperty.Render(m.renderValue(p, false))))
+			} else if p.value == "" {
+				b.WriteString(marginLeft.Render(m.Styles.Label.Render(m.renderValue(p, false))))
+			} else if m.cursor == offset+i {
+				b.WriteString(marginLeft.Render(m.Styles.SelectedProperty.Render(p.value)))
+			} else {
+				b.WriteString(marginLeft.Render(p.value))
+			}
 		}
 	}
 	return b.String()
 }
 
-func (m *Model) renderNotes() string {
-	var b strings.Builder
-	b.WriteString(m.Styles.Subtitle.Render("Notes") + "\n")
-	b.WriteString(marginLeft.Render(m.Item.Notes))
-	return b.String()
+func (m *Model) renderValue(p property, isSelected bool) string {
+	if p.value == "" {
+		switch p.kind {
+		case FIELDS:
+			return "(empty)"
+		case TOTP:
+			return "(invalid secret)"
+		}
+		return "(no " + strings.ToLower(p.label) + ")"
+	}
+	if isSelected {
+		return m.Styles.SelectedProperty.Render(p.value)
+	}
+	if p.hidden {
+		return strings.Repeat("•", 4)
+	}
+	if p.kind == TOTP && len(p.value) == 6 {
+		return p.value[:3] + " " + p.value[3:]
+	}
+	return p.value
+}
+
+// renderCountdown draws how long the current TOTP code stays valid.
+func (m *Model) renderCountdown() string {
+	key, err := totp.Parse(m.Item.Login.Totp)
+	if err != nil {
+		return ""
+	}
+	const width = 10
+	remaining := key.Remaining(time.Now())
+	filled := int(remaining) * width / (key.Period * int(time.Second))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	return m.Styles.Label.Render(bar + " " + strconv.Itoa(int(remaining.Seconds())) + "s")
 }
 
 func (m Model) View() string {
@@ -371,27 +622,26 @@ func (m Model) View() string {
 	// title
 	title := m.Styles.Title.Copy().MarginLeft(2).Render(item.Name)
 	title += " " + m.statusMessage
-
-	creds := m.renderCreds()
+	if m.statusMessage == "" && m.Clipboard.Pending() {
+		remaining := m.Clipboard.Remaining(time.Now()).Round(time.Second)
+		title += m.Styles.Label.Render("clipboard clears in " + remaining.String())
+	}
 
 	// help
-	helpView := m.Help.View(*newItemKeyMap())
+	helpView := m.Help.View(*m.KeyMap)
+	if m.editing {
+		editKeys := editKeyMap(*m.KeyMap)
+		editKeys.Generate.SetEnabled(m.editingSecret())
+		helpView = m.Help.View(editKeys)
+	}
 
 	// gluing it together
 	var b strings.Builder
 	b.WriteString(title)
-	b.WriteString("\n\n" + creds)
-	if len(item.Fields) > 0 {
-		fields := m.renderFields()
-		b.WriteString("\n" + fields)
-	}
-	if len(item.Login.Uris) > 0 {
-		uris := m.renderUri()
-		b.WriteString("\n\n" + uris)
-	}
-	if item.Notes != "" {
-		notes := m.renderNotes()
-		b.WriteString("\n" + notes)
+	offset := 0
+	for _, s := range layout(item) {
+		b.WriteString("\n\n" + m.renderSection(s, offset))
+		offset += len(s.props)
 	}
 
 	remainingHeight := m.height - (lipgloss.Height(b.String()) + lipgloss.Height(helpView) - 1)
@@ -407,12 +657,10 @@ func (m Model) View() string {
 
 func New() Model {
 	return Model{
-		Item:               bw.Item{},
-		Help:               help.New(),
-		KeyMap:             newItemKeyMap(),
-		cursor:             USERNAME,
-		selectedUriIndex:   0,
-		selectedFieldIndex: 0,
+		Item:      bw.Item{},
+		Help:      help.New(),
+		KeyMap:    newItemKeyMap(),
+		Clipboard: clipboard.NewGuard(clipboard.Detect(""), clipboard.DefaultTimeout),
 	}
 }
 
@@ -426,3 +674,11 @@ func getMax(fields []string) int {
 	}
 	return max
 }
+
+func mapLabels(props []property) []string {
+	labels := make([]string, 0, len(props))
+	for _, p := range props {
+		labels = append(labels, p.label)
+	}
+	return labels
+}
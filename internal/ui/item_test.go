@@ -0,0 +1,160 @@
+package item
+
+import (
+	bw "bitwarden-tui/internal"
+	"bitwarden-tui/internal/clipboard"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeClipboard is a clipboard that only holds a string.
+type fakeClipboard struct{ text string }
+
+func (c *fakeClipboard) Name() string            { return "fake" }
+func (c *fakeClipboard) Read() (string, error)   { return c.text, nil }
+func (c *fakeClipboard) Write(text string) error { c.text = text; return nil }
+
+func newTestModel(item bw.Item) (Model, *fakeClipboard) {
+	board := &fakeClipboard{text: "before"}
+	m := New()
+	m.Clipboard = clipboard.NewGuard(board, clipboard.DefaultTimeout)
+	m.SetSize(80, 24)
+	m.SetItem(item)
+	return m, board
+}
+
+func keyPress(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+var testLogin = bw.Item{
+	Id:    "i1",
+	Type:  bw.TypeLogin,
+	Name:  "GitHub",
+	Login: bw.Login{Username: "octo", Password: "hunter2"},
+}
+
+func TestItemCopiesSelectedProperty(t *testing.T) {
+	m, board := newTestModel(testLogin)
+	if m.Cursor() != USERNAME {
+		t.Fatalf("cursor starts on %v, want USERNAME", m.Cursor())
+	}
+	m, _ = m.Update(keyPress("c"))
+	if board.text != "octo" {
+		t.Errorf("clipboard = %q after copying the username", board.text)
+	}
+	m, _ = m.Update(keyPress("j"))
+	if m.Cursor() != PASSWORD {
+		t.Fatalf("cursor on %v after j, want PASSWORD", m.Cursor())
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if board.text != "hunter2" {
+		t.Errorf("clipboard = %q after copying the password", board.text)
+	}
+	if cmd == nil {
+		t.Fatal("copying returned no command")
+	}
+	if !strings.Contains(m.View(), "copied password") {
+		t.Error("no status message after copying the password")
+	}
+	if err := m.Clipboard.Clear(); err != nil || board.text != "before" {
+		t.Errorf("clipboard = %q, %v after clearing, want what was there before", board.text, err)
+	}
+}
+
+func TestItemHidesPassword(t *testing.T) {
+	m, _ := newTestModel(testLogin)
+	view := m.View()
+	if !strings.Contains(view, "GitHub") || !strings.Contains(view, "octo") {
+		t.Errorf("view lacks the name or username:\n%s", view)
+	}
+	if strings.Contains(view, "hunter2") {
+		t.Errorf("view shows the password:\n%s", view)
+	}
+}
+
+func TestItemMovesCursor(t *testing.T) {
+	item := testLogin
+	item.Login.Uris = []bw.Uri{{Uri: "https://github.com"}}
+	m, _ := newTestModel(item)
+	if m.Cursor() != USERNAME {
+		t.Fatalf("cursor starts on %v, want USERNAME", m.Cursor())
+	}
+	for _, want := range []SelectedProperty{PASSWORD, URI, NOTES, USERNAME} {
+		m, _ = m.Update(keyPress("j"))
+		if m.Cursor() != want {
+			t.Fatalf("cursor on %v after j, want %v", m.Cursor(), want)
+		}
+	}
+	m, _ = m.Update(keyPress("k"))
+	if m.Cursor() != NOTES {
+		t.Errorf("cursor on %v after k from the top, want NOTES", m.Cursor())
+	}
+}
+
+func TestItemUpdateKeepsCursor(t *testing.T) {
+	item := testLogin
+	item.Login.Uris = []bw.Uri{{Uri: "https://github.com"}}
+	m, _ := newTestModel(item)
+	m.CursorDown()
+	m.UpdateItem(item)
+	if m.Cursor() != PASSWORD {
+		t.Errorf("cursor on %v after an update, want it left on PASSWORD", m.Cursor())
+	}
+	// the last row is gone in the newer version
+	m.CursorUp()
+	m.CursorUp()
+	m.UpdateItem(testLogin)
+	if m.Cursor() != NOTES {
+		t.Errorf("cursor on %v after its row went, want the last row", m.Cursor())
+	}
+}
+
+func TestItemTotpTicks(t *testing.T) {
+	item := testLogin
+	item.Login.Totp = "JBSWY3DPEHPK3PXP"
+	m := New()
+	if m.SetItem(item) == nil {
+		t.Fatal("no tick for an item with TOTP")
+	}
+	m, cmd := m.Update(TotpTickMsg{id: m.totpTicks})
+	if cmd == nil {
+		t.Fatal("the countdown stopped after a tick")
+	}
+	if _, cmd := m.Update(TotpTickMsg{id: m.totpTicks - 1}); cmd != nil {
+		t.Error("a tick for the previous item went on")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, cmd := m.Update(TotpTickMsg{id: m.totpTicks - 1}); cmd != nil {
+		t.Error("the countdown went on after leaving the item")
+	}
+}
+
+func TestItemNothingToCopy(t *testing.T) {
+	m, board := newTestModel(testLogin)
+	// the notes row is there, empty, below the login
+	for i := 0; m.Cursor() != NOTES; i++ {
+		if i == len(m.properties()) {
+			t.Fatal("no notes row")
+		}
+		m.CursorDown()
+	}
+	m, _ = m.Update(keyPress("c"))
+	if board.text != "before" {
+		t.Errorf("clipboard = %q after copying empty notes", board.text)
+	}
+	if !strings.Contains(m.View(), "nothing to copy") {
+		t.Error("no status message after copying empty notes")
+	}
+}
+
+func TestItemReset(t *testing.T) {
+	m, _ := newTestModel(testLogin)
+	m.CursorDown()
+	m.Reset()
+	if m.Item.Id != "" || m.cursor != 0 {
+		t.Errorf("after Reset the item is %q with the cursor at %d", m.Item.Id, m.cursor)
+	}
+}
@@ -0,0 +1,303 @@
+package item
+
+import (
+	bw "bitwarden-tui/internal"
+	"bitwarden-tui/internal/totp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SelectedProperty int
+
+const (
+	USERNAME SelectedProperty = iota
+	PASSWORD
+	FIELDS
+	URI
+	NOTES
+	TOTP
+	CARDHOLDER
+	BRAND
+	NUMBER
+	EXPIRATION
+	CODE
+	IDENTITY
+	ATTACHMENT
+	HISTORY
+)
+
+type sectionKind int
+
+const (
+	// labelledSection renders "label value" rows with aligned labels.
+	labelledSection sectionKind = iota
+	// linkSection renders bare values, shortened to their host.
+	linkSection
+	// textSection renders one free-form block of text.
+	textSection
+)
+
+// property is a single selectable, copyable row of the item view.
+type property struct {
+	kind   SelectedProperty
+	label  string
+	value  string
+	hidden bool
+	// index into Item.Fields, Login.Uris or Attachments for FIELDS, URI and
+	// ATTACHMENT
+	index int
+	// set writes an edited value back into the item, nil if read-only
+	set func(i *bw.Item, v string)
+}
+
+// copyName is how the property is called in the status line after copying.
+func (p property) copyName() string {
+	switch p.kind {
+	case FIELDS:
+		return "field"
+	case URI:
+		return "url"
+	case NOTES:
+		return "notes"
+	case TOTP:
+		return "verification code"
+	case HISTORY:
+		return "old password"
+	}
+	return strings.ToLower(p.label)
+}
+
+type section struct {
+	title string
+	kind  sectionKind
+	props []property
+}
+
+// layout lists the sections shown for an item, in cursor order. Each item
+// type has its own layout; fields and notes are shared by all of them.
+func layout(i bw.Item) []section {
+	var sections []section
+	switch i.Type {
+	case bw.TypeLogin:
+		sections = loginLayout(i)
+	case bw.TypeCard:
+		sections = cardLayout(i)
+	case bw.TypeIdentity:
+		sections = identityLayout(i)
+	}
+	if len(i.Fields) > 0 {
+		sections = append(sections, fieldsSection(i))
+	}
+	if i.Type == bw.TypeLogin && len(i.Login.Uris) > 0 {
+		sections = append(sections, uriSection(i))
+	}
+	if len(i.PasswordHistory) > 0 {
+		sections = append(sections, historySection(i))
+	}
+	if len(i.Attachments) > 0 {
+		sections = append(sections, attachmentsSection(i))
+	}
+	// shown even when empty, so that notes can be added
+	sections = append(sections, section{
+		title: "Notes",
+		kind:  textSection,
+		props: []property{{
+			kind:  NOTES,
+			label: "Notes",
+			value: i.Notes,
+			set:   func(i *bw.Item, v string) { i.Notes = v },
+		}},
+	})
+	return sections
+}
+
+func loginLayout(i bw.Item) []section {
+	s := section{
+		kind: labelledSection,
+		props: []property{
+			{
+				kind:  USERNAME,
+				label: "Username",
+				value: i.Login.Username,
+				set:   func(i *bw.Item, v string) { i.Login.Username = v },
+			},
+			{
+				kind:   PASSWORD,
+				label:  "Password",
+				value:  i.Login.Password,
+				hidden: true,
+				set:    func(i *bw.Item, v string) { i.Login.Password = v },
+			},
+		},
+	}
+	if i.Login.Totp != "" {
+		p := property{kind: TOTP, label: "TOTP"}
+		if key, err := totp.Parse(i.Login.Totp); err == nil {
+			p.value = key.Code(time.Now())
+		}
+		s.props = append(s.props, p)
+	}
+	return []section{s}
+}
+
+func cardLayout(i bw.Item) []section {
+	c := i.Card
+	expiration := c.ExpMonth
+	if c.ExpYear != "" {
+		if expiration != "" {
+			expiration += "/"
+		}
+		expiration += c.ExpYear
+	}
+	props := nonEmpty([]property{
+		{
+			kind:  CARDHOLDER,
+			label: "Cardholder name",
+			value: c.CardholderName,
+			set:   func(i *bw.Item, v string) { i.Card.CardholderName = v },
+		},
+		{
+			kind:  BRAND,
+			label: "Brand",
+			value: c.Brand,
+			set:   func(i *bw.Item, v string) { i.Card.Brand = v },
+		},
+		{
+			kind:   NUMBER,
+			label:  "Number",
+			value:  c.Number,
+			hidden: true,
+			set:    func(i *bw.Item, v string) { i.Card.Number = v },
+		},
+		{kind: EXPIRATION, label: "Expiration", value: expiration},
+		{
+			kind:   CODE,
+			label:  "Security code",
+			value:  c.Code,
+			hidden: true,
+			set:    func(i *bw.Item, v string) { i.Card.Code = v },
+		},
+	})
+	if len(props) == 0 {
+		return nil
+	}
+	return []section{{kind: labelledSection, props: props}}
+}
+
+func identityLayout(i bw.Item) []section {
+	id := i.Identity
+	var sections []section
+	personal := nonEmpty([]property{
+		{kind: IDENTITY, label: "Name", value: id.FullName()},
+		identityProperty("Username", id.Username, false, func(id *bw.Identity, v string) { id.Username = v }),
+		identityProperty("Company", id.Company, false, func(id *bw.Identity, v string) { id.Company = v }),
+		identityProperty("SSN", id.Ssn, true, func(id *bw.Identity, v string) { id.Ssn = v }),
+		identityProperty("Passport number", id.PassportNumber, true, func(id *bw.Identity, v string) { id.PassportNumber = v }),
+		identityProperty("License number", id.LicenseNumber, false, func(id *bw.Identity, v string) { id.LicenseNumber = v }),
+	})
+	if len(personal) > 0 {
+		sections = append(sections, section{kind: labelledSection, props: personal})
+	}
+	contact := nonEmpty([]property{
+		identityProperty("Email", id.Email, false, func(id *bw.Identity, v string) { id.Email = v }),
+		identityProperty("Phone", id.Phone, false, func(id *bw.Identity, v string) { id.Phone = v }),
+		identityProperty("Address 1", id.Address1, false, func(id *bw.Identity, v string) { id.Address1 = v }),
+		identityProperty("Address 2", id.Address2, false, func(id *bw.Identity, v string) { id.Address2 = v }),
+		identityProperty("Address 3", id.Address3, false, func(id *bw.Identity, v string) { id.Address3 = v }),
+		identityProperty("City", id.City, false, func(id *bw.Identity, v string) { id.City = v }),
+		identityProperty("State", id.State, false, func(id *bw.Identity, v string) { id.State = v }),
+		identityProperty("Postal code", id.PostalCode, false, func(id *bw.Identity, v string) { id.PostalCode = v }),
+		identityProperty("Country", id.Country, false, func(id *bw.Identity, v string) { id.Country = v }),
+	})
+	if len(contact) > 0 {
+		sections = append(sections, section{title: "Contact", kind: labelledSection, props: contact})
+	}
+	return sections
+}
+
+func identityProperty(label, value string, hidden bool, set func(id *bw.Identity, v string)) property {
+	return property{
+		kind:   IDENTITY,
+		label:  label,
+		value:  value,
+		hidden: hidden,
+		set:    func(i *bw.Item, v string) { set(&i.Identity, v) },
+	}
+}
+
+func fieldsSection(i bw.Item) section {
+	s := section{kind: labelledSection}
+	for idx, f := range i.Fields {
+		idx := idx
+		s.props = append(s.props, property{
+			kind:   FIELDS,
+			label:  f.Name,
+			value:  f.Value,
+			hidden: f.Type == 1,
+			index:  idx,
+			set:    func(i *bw.Item, v string) { i.Fields[idx].Value = v },
+		})
+	}
+	return s
+}
+
+func uriSection(i bw.Item) section {
+	s := section{title: "URIs", kind: linkSection}
+	for idx, u := range i.Login.Uris {
+		idx := idx
+		s.props = append(s.props, property{
+			kind:  URI,
+			label: "URI " + strconv.Itoa(idx+1),
+			value: u.Uri,
+			index: idx,
+			set:   func(i *bw.Item, v string) { i.Login.Uris[idx].Uri = v },
+		})
+	}
+	return s
+}
+
+// historySection lists earlier passwords by when they were replaced.
+func historySection(i bw.Item) section {
+	s := section{title: "Password history", kind: labelledSection}
+	if date := i.Login.PasswordRevisionDate; date != nil {
+		s.title += " · current since " + date.Local().Format("2006-01-02 15:04")
+	}
+	for idx, h := range i.PasswordHistory {
+		label := "unknown date"
+		if h.LastUsedDate != nil {
+			label = h.LastUsedDate.Local().Format("2006-01-02 15:04")
+		}
+		s.props = append(s.props, property{
+			kind:   HISTORY,
+			label:  label,
+			value:  h.Password,
+			hidden: true,
+			index:  idx,
+		})
+	}
+	return s
+}
+
+func attachmentsSection(i bw.Item) section {
+	s := section{title: "Attachments", kind: labelledSection}
+	for idx, a := range i.Attachments {
+		s.props = append(s.props, property{
+			kind:  ATTACHMENT,
+			label: a.FileName,
+			value: a.SizeName,
+			index: idx,
+		})
+	}
+	return s
+}
+
+func nonEmpty(props []property) []property {
+	filtered := make([]property, 0, len(props))
+	for _, p := range props {
+		if p.value != "" {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}